@@ -0,0 +1,53 @@
+package www
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// WithAuthProvider sets fn to authenticate every request of the client
+// that has no Authorization header of its own. It runs before the
+// OnBeforeRequest hooks, once the request is prepared, and sets the
+// header of the embedded http.Request (r.Header). An error fails the
+// request before it is sent.
+func (cl *StandardClient) WithAuthProvider(fn func(r *Request) error) *StandardClient {
+	cl.authProvider = fn
+	return cl
+}
+
+// BearerTokenProvider returns an auth provider sending the token fetch
+// returns (e.g. from an OAuth2 client credentials grant) as a bearer
+// token. The token is kept until a minute before its expiry, a zero
+// expiry keeps it for good, and fetch is called by one request at a time
+// with the context of that request.
+func BearerTokenProvider(fetch func(ctx context.Context) (token string, expiry time.Time, err error)) func(*Request) error {
+	var mu sync.Mutex
+	var token string
+	var expiry time.Time
+
+	return func(r *Request) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		now := r.client.now()
+		if token == "" || !expiry.IsZero() && !now.Add(time.Minute).Before(expiry) {
+			t, exp, err := fetch(r.Request.Context())
+			if err != nil {
+				return err
+			}
+			token, expiry = t, exp
+		}
+		r.Request.Header.Set("Authorization", "Bearer "+token)
+		return nil
+	}
+}
+
+func (r *Request) authenticate() {
+	if r.client.authProvider == nil || r.Request.Header.Get("Authorization") != "" {
+		return
+	}
+	if err := r.client.authProvider(r); err != nil {
+		r.err = err
+	}
+}
@@ -0,0 +1,49 @@
+package www
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAuthProvider(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("Authorization")))
+	}))
+	defer srv.Close()
+
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	var fetched int
+	provider := BearerTokenProvider(func(ctx context.Context) (string, time.Time, error) {
+		fetched++
+		return fmt.Sprintf("token%d", fetched), clock.Now().Add(time.Hour), nil
+	})
+	cl := NewClient().WithClock(clock).WithAuthProvider(provider)
+
+	for i, want := range []string{"Bearer token1", "Bearer token1"} {
+		if got := NewRequest(cl).Get(srv.URL).Text(); got != want {
+			t.Errorf("%d:got %q, want %q", i, got, want)
+		}
+	}
+	clock.now = clock.now.Add(59*time.Minute + time.Second)
+	if got := NewRequest(cl).Get(srv.URL).Text(); got != "Bearer token2" {
+		t.Errorf("expiring:got %q, want %q", got, "Bearer token2")
+	}
+	if got := NewRequest(cl).WithBasicAuth("user", "pass").Get(srv.URL).Text(); got != "Basic dXNlcjpwYXNz" {
+		t.Errorf("own header:got %q", got)
+	}
+	if fetched != 2 {
+		t.Errorf("fetched:got %d, want 2", fetched)
+	}
+
+	failed := errors.New("no credentials")
+	cl = NewClient().WithAuthProvider(func(*Request) error { return failed })
+	if resp := NewRequest(cl).Get(srv.URL); !errors.Is(resp.Error(), failed) {
+		t.Errorf("Error:got %v, want %v", resp.Error(), failed)
+	}
+}
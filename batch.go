@@ -0,0 +1,125 @@
+package www
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// Batch sends requests in parallel, see StandardClient.NewBatch.
+type Batch struct {
+	reqs     []*Request
+	failFast bool
+}
+
+// NewBatch returns an empty batch of requests of the client:
+//
+//	results := cl.NewBatch().
+//		Add(www.NewRequest(cl).Prepare(http.MethodGet, usersURL)).
+//		Add(www.NewRequest(cl).Json(order).Prepare(http.MethodPost, ordersURL)).
+//		Run(ctx, 4)
+//	defer results.Close()
+//	if err := results.Err(); err != nil {
+//		...
+//	}
+func (cl *StandardClient) NewBatch() *Batch {
+	return &Batch{}
+}
+
+// Add queues requests given their method and URL with Prepare. Run sends
+// a Clone of every request, so a builder can be reused with its replay
+// constraints.
+func (b *Batch) Add(reqs ...*Request) *Batch {
+	b.reqs = append(b.reqs, reqs...)
+	return b
+}
+
+// FailFast makes Run cancel the requests not done yet once a request
+// fails (see Response.Error), they fail with context.Canceled. A 4xx or
+// 5xx response is not a failure, the server answered.
+func (b *Batch) FailFast() *Batch {
+	b.failFast = true
+	return b
+}
+
+// Run sends the requests with up to concurrency of them in flight, all of
+// them when concurrency is 0, with the context ctx and returns the
+// responses in the order of Add. Every request runs the whole pipeline of
+// Do, retries included; the bodies of the responses must be closed.
+func (b *Batch) Run(ctx context.Context, concurrency int) BatchResults {
+	results := make(BatchResults, len(b.reqs))
+	if concurrency < 1 || concurrency > len(b.reqs) {
+		concurrency = len(b.reqs)
+	}
+
+	// a context per request, so failing fast spares the responses done
+	ctxs := make([]context.Context, len(b.reqs))
+	cancels := make([]context.CancelFunc, len(b.reqs))
+	for i := range b.reqs {
+		ctxs[i], cancels[i] = context.WithCancel(ctx)
+	}
+	var mu sync.Mutex
+	done := make([]bool, len(b.reqs))
+	failed := false
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				req := b.reqs[i]
+				resp := req.Clone().WithContext(ctxs[i]).Do(req.method, req.uri)
+				if resp.Response == nil {
+					cancels[i]()
+				} else {
+					resp.Body = &cancelBody{resp.Body, cancels[i]}
+				}
+
+				mu.Lock()
+				results[i] = resp
+				done[i] = true
+				if b.failFast && resp.err != nil && !failed {
+					failed = true
+					for j, cancel := range cancels {
+						if !done[j] {
+							cancel()
+						}
+					}
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for i := range b.reqs {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
+
+// BatchResults are the responses of Batch.Run in the order of Add.
+type BatchResults []*Response
+
+// Err returns the errors of the failed requests joined, nil when all
+// of them succeeded, whatever the status of the responses.
+func (rs BatchResults) Err() error {
+	var errs []error
+	for _, resp := range rs {
+		if resp.err != nil {
+			errs = append(errs, resp.err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close closes the bodies of the responses.
+func (rs BatchResults) Close() {
+	for _, resp := range rs {
+		if resp.Response != nil {
+			resp.Body.Close()
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package www
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBatch(t *testing.T) {
+
+	var inFlight, maxInFlight int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			max := atomic.LoadInt32(&maxInFlight)
+			if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+				break
+			}
+		}
+		switch r.URL.Path {
+		case "/block":
+			<-r.Context().Done()
+			return
+		case "/404":
+			http.NotFound(w, r)
+			return
+		}
+		ms, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/"))
+		time.Sleep(time.Duration(ms) * time.Millisecond)
+		fmt.Fprintf(w, "%s %s", r.Method, r.URL.Path)
+	}))
+	defer srv.Close()
+
+	t.Run("ORDER", func(t *testing.T) {
+		atomic.StoreInt32(&maxInFlight, 0)
+		cl := NewClient()
+		batch := cl.NewBatch()
+		for _, ms := range []int{30, 20, 10, 0} {
+			batch.Add(NewRequest(cl).Prepare(http.MethodGet, fmt.Sprintf("%s/%d", srv.URL, ms)))
+		}
+		batch.Add(NewRequest(cl).Json(map[string]int{"a": 1}).Prepare(http.MethodPost, srv.URL+"/0"))
+
+		results := batch.Run(context.Background(), 2)
+		if err := results.Err(); err != nil {
+			t.Fatalf("%v", err)
+		}
+		var got []string
+		for _, resp := range results {
+			got = append(got, resp.Text())
+		}
+		if want := "[GET /30 GET /20 GET /10 GET /0 POST /0]"; fmt.Sprint(got) != want {
+			t.Errorf("Results:got %v, want %v", got, want)
+		}
+		if max := atomic.LoadInt32(&maxInFlight); max != 2 {
+			t.Errorf("concurrency:got %d, want 2", max)
+		}
+	})
+
+	t.Run("ERRORS", func(t *testing.T) {
+		cl := NewClient()
+		results := cl.NewBatch().
+			Add(NewRequest(cl).Prepare(http.MethodGet, srv.URL+"/0")).
+			Add(NewRequest(cl).Prepare(http.MethodGet, "http://[::1]:namedport")).
+			Run(context.Background(), 0)
+		defer results.Close()
+		if results[0].Error() != nil || results[1].Error() == nil || results.Err() == nil {
+			t.Errorf("got %v, %v, %v", results[0].Error(), results[1].Error(), results.Err())
+		}
+	})
+
+	t.Run("FAIL FAST", func(t *testing.T) {
+		cl := NewClient()
+		block := NewRequest(cl).Prepare(http.MethodGet, srv.URL+"/block")
+		results := cl.NewBatch().FailFast().
+			Add(NewRequest(cl).Prepare(http.MethodGet, srv.URL+"/404"), block, block).
+			Add(NewRequest(cl).Prepare(http.MethodGet, "http://[::1]:namedport"), block).
+			Run(context.Background(), 3) // the failing request waits for the first one
+		defer results.Close()
+
+		if got := results[0].StatusCode; got != http.StatusNotFound || results[0].Error() != nil {
+			t.Errorf("not a failure:got %d, %v", got, results[0].Error())
+		}
+		for _, i := range []int{1, 2, 4} {
+			if err := results[i].Error(); !errors.Is(err, context.Canceled) {
+				t.Errorf("%d:got %v, want %v", i, err, context.Canceled)
+			}
+		}
+	})
+
+	if results := NewClient().NewBatch().Run(context.Background(), 4); len(results) != 0 || results.Err() != nil {
+		t.Errorf("empty:got %v", results)
+	}
+}
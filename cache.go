@@ -0,0 +1,298 @@
+package www
+
+import (
+	"bytes"
+	"container/list"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CachedResponse is a response kept by a CacheStore, with its decoded
+// body.
+type CachedResponse struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	// the values of the request headers named by Vary
+	Vary http.Header
+	// the response is used without revalidation until Expires
+	Expires time.Time
+}
+
+// CacheStore keeps the responses of WithCache, it must be safe for
+// concurrent use.
+type CacheStore interface {
+	Get(key string) (*CachedResponse, bool)
+	Set(key string, resp *CachedResponse)
+	Delete(key string)
+}
+
+// WithCache caches the 200 responses to GET requests in store. A fresh
+// response (Cache-Control max-age or Expires) is served without a round
+// trip, a stale one is revalidated with If-None-Match and
+// If-Modified-Since when it has an ETag or a Last-Modified. no-store
+// responses are not kept and no-cache ones always revalidated, as are the
+// requests sending Cache-Control: no-cache. A request of another method
+// that succeeds drops the cached response of its URL. Response.FromCache
+// and CacheKey tell the served responses, requests with their own
+// CachedValidators or a Range header bypass the cache.
+func (cl *StandardClient) WithCache(store CacheStore) *StandardClient {
+	cl.cache = store
+	return cl
+}
+
+// LRUCache is an in-memory CacheStore keeping the most recently used
+// responses.
+type LRUCache struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List // of *lruEntry, the most recent first
+	entries  map[string]*list.Element
+}
+
+type lruEntry struct {
+	key  string
+	resp *CachedResponse
+}
+
+// NewLRUCache returns a cache of up to capacity responses.
+func NewLRUCache(capacity int) *LRUCache {
+	return &LRUCache{
+		capacity: capacity,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+func (c *LRUCache) Get(key string) (*CachedResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(*lruEntry).resp, true
+}
+
+func (c *LRUCache) Set(key string, resp *CachedResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[key]; ok {
+		elem.Value.(*lruEntry).resp = resp
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&lruEntry{key, resp})
+	for c.order.Len() > c.capacity && c.order.Len() > 0 {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruEntry).key)
+	}
+}
+
+func (c *LRUCache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[key]; ok {
+		c.order.Remove(elem)
+		delete(c.entries, key)
+	}
+}
+
+// Len returns the number of cached responses.
+func (c *LRUCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// cacheable reports whether the request goes through the cache of the
+// client.
+func (r *Request) cacheable() bool {
+	return r.client.cache != nil && r.etag == "" && r.lastModified.IsZero() &&
+		r.Request.Header.Get("Range") == ""
+}
+
+// lookupCache returns the fresh cached response of a GET request, for a
+// stale one it makes the request conditional.
+func (r *Request) lookupCache() *Response {
+	r.cacheKey, r.cacheEntry = "", nil // of a clone sent before
+	if !r.cacheable() || r.Request.Method != http.MethodGet {
+		return nil
+	}
+	key := http.MethodGet + " " + r.Request.URL.String()
+	r.cacheKey = key
+
+	entry, ok := r.client.cache.Get(key)
+	if !ok || !varyMatches(entry.Vary, r.Request.Header) {
+		return nil
+	}
+	noCache := hasDirective(r.Request.Header.Get("Cache-Control"), "no-cache")
+	if !noCache && r.client.now().Before(entry.Expires) {
+		resp := &Response{
+			Response: &http.Response{
+				Status:        strconv.Itoa(entry.StatusCode) + " " + http.StatusText(entry.StatusCode),
+				StatusCode:    entry.StatusCode,
+				Proto:         "HTTP/1.1",
+				ProtoMajor:    1,
+				ProtoMinor:    1,
+				Header:        entry.Header.Clone(),
+				Body:          ioutil.NopCloser(bytes.NewReader(entry.Body)),
+				ContentLength: int64(len(entry.Body)),
+				Request:       r.Request,
+			},
+			request:   r,
+			fromCache: true,
+			cacheKey:  key,
+		}
+		resp.countBody()
+		return resp
+	}
+
+	r.cacheEntry = entry
+	if etag := entry.Header.Get("ETag"); etag != "" {
+		r.Request.Header.Set("If-None-Match", etag)
+	}
+	if lastModified := entry.Header.Get("Last-Modified"); lastModified != "" {
+		r.Request.Header.Set("If-Modified-Since", lastModified)
+	}
+	return nil
+}
+
+// storeCache keeps the response in the cache once its body is read to
+// the end, answers a revalidation with the cached body and drops the
+// cached response of a URL changed by another method.
+func (resp *Response) storeCache() {
+	r := resp.request
+	if resp.Response == nil || r == nil || !r.cacheable() {
+		return
+	}
+	if r.Request.Method != http.MethodGet && r.Request.Method != http.MethodHead {
+		if resp.StatusCode < http.StatusBadRequest {
+			r.client.cache.Delete(http.MethodGet + " " + r.Request.URL.String())
+		}
+		return
+	}
+	if r.cacheKey == "" {
+		return
+	}
+	resp.cacheKey = r.cacheKey
+
+	if resp.StatusCode == http.StatusNotModified && r.cacheEntry != nil {
+		entry := *r.cacheEntry
+		entry.Header = entry.Header.Clone()
+		for key, values := range resp.Header {
+			if key != "Content-Length" {
+				entry.Header[key] = values
+			}
+		}
+		entry.Expires = r.freshUntil(entry.Header)
+		r.client.cache.Set(r.cacheKey, &entry)
+
+		resp.Body.Close()
+		resp.notModified = true
+		resp.fromCache = true
+		resp.StatusCode = entry.StatusCode
+		resp.Status = strconv.Itoa(entry.StatusCode) + " " + http.StatusText(entry.StatusCode)
+		resp.Header = entry.Header.Clone()
+		resp.Body = ioutil.NopCloser(bytes.NewReader(entry.Body))
+		resp.ContentLength = int64(len(entry.Body))
+		return
+	}
+
+	cacheControl := resp.Header.Get("Cache-Control")
+	if resp.StatusCode != http.StatusOK || hasDirective(cacheControl, "no-store") ||
+		resp.Header.Get("Vary") == "*" {
+		return
+	}
+	expires := r.freshUntil(resp.Header)
+	if !expires.After(r.client.now()) && resp.Header.Get("ETag") == "" && resp.Header.Get("Last-Modified") == "" {
+		return
+	}
+
+	entry := &CachedResponse{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header.Clone(),
+		Vary:       http.Header{},
+		Expires:    expires,
+	}
+	for _, field := range strings.Split(resp.Header.Get("Vary"), ",") {
+		if field = http.CanonicalHeaderKey(strings.TrimSpace(field)); field != "" {
+			entry.Vary[field] = r.Request.Header.Values(field)
+		}
+	}
+	resp.Body = &cacheBody{ReadCloser: resp.Body, store: r.client.cache, key: r.cacheKey, entry: entry}
+}
+
+// freshUntil returns the end of the freshness lifetime a response with
+// header gets now.
+func (r *Request) freshUntil(header http.Header) time.Time {
+	now := r.client.now()
+	cacheControl := header.Get("Cache-Control")
+	if hasDirective(cacheControl, "no-cache") {
+		return now
+	}
+	for _, directive := range strings.Split(cacheControl, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		if strings.EqualFold(name, "max-age") {
+			seconds, err := strconv.Atoi(value)
+			if err != nil {
+				return now
+			}
+			age, _ := strconv.Atoi(header.Get("Age"))
+			return now.Add(time.Duration(seconds-age) * time.Second)
+		}
+	}
+	if expires, err := http.ParseTime(header.Get("Expires")); err == nil {
+		if date, err := http.ParseTime(header.Get("Date")); err == nil {
+			return now.Add(expires.Sub(date))
+		}
+		return expires
+	}
+	return now
+}
+
+func hasDirective(cacheControl, name string) bool {
+	for _, directive := range strings.Split(cacheControl, ",") {
+		directive, _, _ = strings.Cut(strings.TrimSpace(directive), "=")
+		if strings.EqualFold(directive, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func varyMatches(vary, header http.Header) bool {
+	for field, values := range vary {
+		if strings.Join(values, ",") != strings.Join(header.Values(field), ",") {
+			return false
+		}
+	}
+	return true
+}
+
+// cacheBody stores the response once it is read to the end.
+type cacheBody struct {
+	io.ReadCloser
+	buf   bytes.Buffer
+	store CacheStore
+	key   string
+	entry *CachedResponse
+}
+
+func (b *cacheBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.buf.Write(p[:n])
+	if err == io.EOF && b.entry != nil {
+		b.entry.Body = b.buf.Bytes()
+		b.store.Set(b.key, b.entry)
+		b.entry = nil
+	}
+	return n, err
+}
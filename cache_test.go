@@ -0,0 +1,98 @@
+package www
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCache(t *testing.T) {
+
+	var hits, revalidated int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		if r.Method == http.MethodPost {
+			return
+		}
+		if r.URL.Path == "/private" {
+			w.Header().Set("Cache-Control", "no-store")
+		} else {
+			w.Header().Set("Cache-Control", "max-age=60")
+			w.Header().Set("ETag", `"v1"`)
+		}
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			revalidated++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"name":"www"}`))
+	}))
+	defer srv.Close()
+
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	store := NewLRUCache(1)
+	cl := NewClient().WithClock(clock).WithCache(store)
+
+	get := func(path string) (*Response, string) {
+		resp := NewRequest(cl).Get(srv.URL + path)
+		var v struct{ Name string }
+		resp.Json(&v)
+		return resp, v.Name
+	}
+
+	resp, name := get("/")
+	if resp.FromCache() || name != "www" || resp.CacheKey() != "GET "+srv.URL+"/" {
+		t.Errorf("first:got FromCache %v, %q, key %q", resp.FromCache(), name, resp.CacheKey())
+	}
+
+	resp, name = get("/")
+	if !resp.FromCache() || name != "www" || hits != 1 {
+		t.Errorf("fresh:got FromCache %v, %q after %d hits", resp.FromCache(), name, hits)
+	}
+
+	clock.now = clock.now.Add(61 * time.Second)
+	resp, name = get("/")
+	if !resp.FromCache() || !resp.NotModified() || name != "www" || revalidated != 1 || resp.StatusCode != http.StatusOK {
+		t.Errorf("stale:got %d, FromCache %v, NotModified %v, %q after %d revalidations",
+			resp.StatusCode, resp.FromCache(), resp.NotModified(), name, revalidated)
+	}
+	hits = 0
+	if resp, _ = get("/"); !resp.FromCache() || hits != 0 {
+		t.Errorf("refreshed:got FromCache %v after %d hits", resp.FromCache(), hits)
+	}
+
+	t.Run("NO-CACHE REQUEST", func(t *testing.T) {
+		revalidated = 0
+		resp := NewRequest(cl).WithHeader("Cache-Control", "no-cache").Get(srv.URL + "/")
+		if resp.Text() != `{"name":"www"}` || revalidated != 1 {
+			t.Errorf("got %q after %d revalidations", resp.Text(), revalidated)
+		}
+	})
+
+	t.Run("NO-STORE", func(t *testing.T) {
+		get("/private")
+		hits = 0
+		if resp, _ := get("/private"); resp.FromCache() || hits != 1 {
+			t.Errorf("got FromCache %v after %d hits", resp.FromCache(), hits)
+		}
+	})
+
+	t.Run("INVALIDATE", func(t *testing.T) {
+		get("/")
+		NewRequest(cl).Post(srv.URL + "/")
+		hits = 0
+		if resp, _ := get("/"); resp.FromCache() || hits != 1 {
+			t.Errorf("got FromCache %v after %d hits", resp.FromCache(), hits)
+		}
+	})
+
+	t.Run("LRU", func(t *testing.T) {
+		get("/")
+		get("/other")
+		if _, ok := store.Get("GET " + srv.URL + "/"); ok || store.Len() != 1 {
+			t.Errorf("got %d responses, the oldest kept %v", store.Len(), ok)
+		}
+	})
+}
@@ -0,0 +1,103 @@
+package www
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+)
+
+var (
+	ErrorNotSent     = errors.New("the request was not sent yet")
+	ErrorNotBuffered = errors.New("the request body is not buffered")
+)
+
+// headers replaced by Redacted in a CapturedRequest
+var sensitiveHeaders = []string{
+	"Authorization",
+	"Proxy-Authorization",
+	"Cookie",
+	"X-Api-Key",
+	"X-Auth-Token",
+}
+
+const Redacted = "[REDACTED]"
+
+// CapturedRequest is a serializable snapshot of a sent request, e.g. to
+// attach a reproducible request to a bug report.
+type CapturedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Host   string      `json:"host,omitempty"`
+	Header http.Header `json:"header"`
+	Body   []byte      `json:"body,omitempty"`
+}
+
+// Capture snapshots the method, URL, headers and body of the sent
+// request, with the credentials headers replaced by Redacted. Only
+// buffered bodies (Json, WithForm, ...) can be captured, a streaming body
+// fails with ErrorNotBuffered and files are closed once Do is done.
+func (r *Request) Capture() (*CapturedRequest, error) {
+	if r.Request == nil {
+		return nil, ErrorNotSent
+	}
+
+	captured := &CapturedRequest{
+		Method: r.Request.Method,
+		URL:    r.Request.URL.String(),
+		Header: r.Request.Header.Clone(),
+	}
+	if r.Request.Host != r.Request.URL.Host {
+		captured.Host = r.Request.Host
+	}
+	for _, key := range sensitiveHeaders {
+		if _, ok := captured.Header[key]; ok {
+			captured.Header.Set(key, Redacted)
+		}
+	}
+
+	if r.Request.GetBody == nil {
+		if r.Request.Body != nil && r.Request.Body != http.NoBody {
+			return nil, ErrorNotBuffered
+		}
+		return captured, nil
+	}
+	body, err := r.Request.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	if captured.Body, err = ioutil.ReadAll(body); err != nil {
+		return nil, err
+	}
+	if len(captured.Body) == 0 {
+		captured.Body = nil
+	}
+
+	return captured, nil
+}
+
+// Replay sends a captured request again as is, redacted headers must be
+// filled in by the caller.
+func (cl *StandardClient) Replay(captured *CapturedRequest) *Response {
+	r := NewRequest(cl)
+	r.method, r.uri = captured.Method, captured.URL
+
+	var err error
+	r.Request, err = http.NewRequest(captured.Method, captured.URL, bytes.NewReader(captured.Body))
+	if err != nil {
+		return &Response{err: err}
+	}
+	if captured.Header != nil {
+		r.Request.Header = captured.Header.Clone()
+	}
+	if captured.Host != "" {
+		r.Request.Host = captured.Host
+	}
+	if len(captured.Body) == 0 {
+		r.Request.Body, r.Request.GetBody = http.NoBody, nil
+		r.Request.ContentLength = 0
+	}
+
+	return r.send()
+}
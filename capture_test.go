@@ -0,0 +1,64 @@
+package www
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestCapture(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		fmt.Fprintf(w, "%s %s %s %s", r.Method, r.URL.RawQuery, r.Header.Get("Authorization"), body)
+	}))
+	defer srv.Close()
+
+	cl := NewClient()
+	r := NewRequest(cl).
+		WithQuery(&url.Values{"q": {"go"}}).
+		JSON(map[string]string{"key": "value"})
+	want := r.Post(srv.URL, http.Header{"Authorization": {"Bearer secret"}}).Text()
+
+	captured, err := r.Capture()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if got := captured.Header.Get("Authorization"); got != Redacted {
+		t.Errorf("Authorization:got %q, want %q", got, Redacted)
+	}
+
+	data, err := json.Marshal(captured)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	var decoded CapturedRequest
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	decoded.Header.Set("Authorization", "Bearer secret")
+	if got := cl.Replay(&decoded).Text(); got != want {
+		t.Errorf("Replay:got %q, want %q", got, want)
+	}
+
+	t.Run("NOT SENT", func(t *testing.T) {
+		if _, err := NewRequest(cl).Capture(); !errors.Is(err, ErrorNotSent) {
+			t.Errorf("got %v, want %v", err, ErrorNotSent)
+		}
+	})
+
+	t.Run("STREAMING", func(t *testing.T) {
+		r := NewRequest(cl).WithFile(io.MultiReader(strings.NewReader("data")))
+		r.Post(srv.URL)
+		if _, err := r.Capture(); !errors.Is(err, ErrorNotBuffered) {
+			t.Errorf("got %v, want %v", err, ErrorNotBuffered)
+		}
+	})
+}
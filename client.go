@@ -4,14 +4,27 @@ package www
 
 import (
 	//"fmt"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/cookiejar"
 	"net/url"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/hashicorp/go-cleanhttp"
 )
 
+var ErrorNotTransport = errors.New("the client transport is not an *http.Transport")
+
+var (
+	ErrRedirectLoop     = errors.New("the redirect chain loops")
+	ErrTooManyRedirects = errors.New("too many redirects")
+)
+
 type ClientOptions map[string]interface{}
 
 func (c ClientOptions) Merge(other ClientOptions) {
@@ -23,9 +36,47 @@ func (c ClientOptions) Merge(other ClientOptions) {
 type StandardClient struct {
 	*http.Client
 	Logger interface{}
-	err    error
+	// relative request URIs are resolved against BaseURL when it is set
+	BaseURL string
+	// sent with every request, headers set on the request win
+	DefaultHeaders http.Header
+	err            error
+	strict         bool
+	// time format of time.Time fields in encoded structs
+	timeFormat string
+	// limit of the (decompressed) response body size
+	maxResponseSize int64
+	metrics         func(RequestMetric)
+	hostMapping     map[string]string
+	bodyTransforms  []func(io.ReadCloser) io.ReadCloser
+	beforeRequest   []func(*Request) error
+	afterResponse   []func(*Request, *Response) error
+	authProvider    func(*Request) error
+	debug           bool
+	cache           CacheStore
+	limits          []*limitGroup
+	autoTranscode   bool
+	acceptCharset   string
+	acceptEncoding  string
+	accept          string
+	jsonAccept      bool
+	compress        string
+	stripHopByHop   bool
+	clock           Clock
+	retry           *RetryPolicy
+	pool            *poolCounters
+	strictLength    bool
+	rawBodies       bool
+	// 0 for the default of 10 redirects, -1 for none
+	maxRedirects int
+	// stop the background work of options on Close
+	closers []func()
+	closed  bool
 }
 
+// guards closers and closed of all clients
+var closeMu sync.Mutex
+
 func New() *Request {
 	return NewRequest(Cleaned())
 }
@@ -120,3 +171,303 @@ func (cl *StandardClient) WithLogger(logger Logger) *StandardClient {
 	cl.Logger = logger
 	return cl
 }
+
+// WithStrict makes every request validate itself before it is sent,
+// see Request.Validate.
+func (cl *StandardClient) WithStrict(strict bool) *StandardClient {
+	cl.strict = strict
+	return cl
+}
+
+// WithTimeFormat sets how time.Time fields of encoded structs (see Query)
+// are encoded: TimeRFC3339 (by default), TimeUnix, TimeUnixMilli or a custom
+// time.Format layout. The `layout` tag or a format option of the field tag
+// overrides it, e.g. `url:"since,unix"`.
+func (cl *StandardClient) WithTimeFormat(format string) *StandardClient {
+	cl.timeFormat = format
+	return cl
+}
+
+// WithMaxResponseSize limits the size of response bodies read by Content and
+// Text, reading more than n bytes fails with ErrBodyTooLarge. For compressed
+// responses the limit applies to the decompressed data.
+func (cl *StandardClient) WithMaxResponseSize(n int64) *StandardClient {
+	cl.maxResponseSize = n
+	return cl
+}
+
+// httpTransport returns the *http.Transport of the client to configure,
+// a client without a transport gets a copy of http.DefaultTransport.
+func (cl *StandardClient) httpTransport() (*http.Transport, error) {
+	switch t := cl.Transport.(type) {
+	case nil:
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		cl.Transport = transport
+		return transport, nil
+	case *http.Transport:
+		return t, nil
+	default:
+		return nil, ErrorNotTransport
+	}
+}
+
+// WithServerName sets the name used for SNI and to verify the server
+// certificate, e.g. when the client connects to an IP address.
+// Unlike the Host header it affects only the TLS handshake.
+func (cl *StandardClient) WithServerName(name string) *StandardClient {
+	return cl.withTLS(func(config *tls.Config) {
+		config.ServerName = name
+	})
+}
+
+// Close closes the idle connections of the transport and stops the
+// background goroutines started by client options. The client must not
+// be used after Close.
+func (cl *StandardClient) Close() error {
+	closeMu.Lock()
+	if cl.closed {
+		closeMu.Unlock()
+		return nil
+	}
+	cl.closed = true
+	closers := cl.closers
+	cl.closers = nil
+	closeMu.Unlock()
+
+	cl.CloseIdleConnections()
+	for _, fn := range closers {
+		fn()
+	}
+	return nil
+}
+
+// onClose registers fn to be called by Close, options starting goroutines
+// use it to stop them. fn is called at once on a closed client.
+func (cl *StandardClient) onClose(fn func()) {
+	closeMu.Lock()
+	if cl.closed {
+		closeMu.Unlock()
+		fn()
+		return
+	}
+	cl.closers = append(cl.closers, fn)
+	closeMu.Unlock()
+}
+
+// WithHostMapping routes requests for a host to another one, e.g.
+// {"api.example.com": "staging.internal:8080"}, while the Host header
+// keeps the original name. A key with a port matches only that port.
+func (cl *StandardClient) WithHostMapping(mapping map[string]string) *StandardClient {
+	cl.hostMapping = mapping
+	return cl
+}
+
+func (cl *StandardClient) mapHost(req *http.Request) {
+	host, ok := cl.hostMapping[req.URL.Host]
+	if !ok {
+		if host, ok = cl.hostMapping[req.URL.Hostname()]; !ok {
+			return
+		}
+		if port := req.URL.Port(); port != "" && !strings.Contains(host, ":") {
+			host += ":" + port
+		}
+	}
+	req.Host = req.URL.Host
+	req.URL.Host = host
+}
+
+// WithBodyTransform adds a transformer of response bodies (decryption,
+// custom decompression, format conversion). The transformers are applied
+// in the order they were added, each wraps the body returned by the
+// previous one, so the first sees the raw body and the last one is what
+// the readers of Response see. The decompression of Content-Encoding
+// happens after them. A transformer must close the body it wraps when it
+// is closed itself.
+func (cl *StandardClient) WithBodyTransform(fn func(io.ReadCloser) io.ReadCloser) *StandardClient {
+	cl.bodyTransforms = append(cl.bodyTransforms, fn)
+	return cl
+}
+
+// WithAcceptEncoding sends the Accept-Encoding header (gzip by default)
+// with every request and on every redirect hop, the bodies are then
+// decompressed by the client (see WithRawBodies). Without it
+// net/http negotiates gzip itself, but not when a request sets the header.
+// Several encodings are listed in the order of preference with quality
+// values, e.g. "deflate;q=1.0, gzip;q=0.8", the server picks the preferred
+// one it supports. Only the encodings the client decodes are accepted,
+// another one fails the requests of the client with
+// ErrorUnsupportedEncoding.
+func (cl *StandardClient) WithAcceptEncoding(encodings ...string) *StandardClient {
+	for _, encoding := range encodings {
+		name := strings.ToLower(strings.TrimSpace(strings.SplitN(encoding, ";", 2)[0]))
+		if !decodedEncodings[name] {
+			cl.err = fmt.Errorf("%w: %s", ErrorUnsupportedEncoding, name)
+			return cl
+		}
+	}
+	cl.acceptEncoding = "gzip"
+	if len(encodings) > 0 {
+		cl.acceptEncoding = qualityList(encodings)
+	}
+	return cl
+}
+
+// decodedEncodings are the content codings decodeBody decompresses.
+var decodedEncodings = map[string]bool{
+	"gzip":     true,
+	"x-gzip":   true,
+	"deflate":  true,
+	"br":       true,
+	"identity": true,
+}
+
+// WithAccept sends the Accept header (application/json by default) with
+// the requests that set none, for servers answering 406 without it.
+// Several media types are listed in the order of preference as with
+// WithAcceptEncoding. It replaces an Accept of DefaultHeaders, while
+// Request.Accept, WithHeader and the headers passed to Do win over it.
+func (cl *StandardClient) WithAccept(mediaTypes ...string) *StandardClient {
+	cl.accept = "application/json"
+	if len(mediaTypes) > 0 {
+		cl.accept = qualityList(mediaTypes)
+	}
+	return cl
+}
+
+// WithJSONAccept makes requests with a Json body accept application/json
+// unless Request.Accept is set, over the default of WithAccept.
+func (cl *StandardClient) WithJSONAccept() *StandardClient {
+	cl.jsonAccept = true
+	return cl
+}
+
+// qualityList lowers the quality value by 0.2 for every next value, down
+// to 0.1, values with an explicit q parameter are kept as is.
+func qualityList(values []string) string {
+	if len(values) == 1 {
+		return values[0]
+	}
+
+	list := make([]string, len(values))
+	for i, val := range values {
+		if strings.Contains(val, ";") {
+			list[i] = val
+			continue
+		}
+		q := 1.0 - 0.2*float64(i)
+		if q < 0.1 {
+			q = 0.1
+		}
+		list[i] = fmt.Sprintf("%s;q=%.1f", val, q)
+	}
+	return strings.Join(list, ", ")
+}
+
+// httpClient returns the client sending the requests of r. Its redirect
+// check re-applies the Accept-Encoding of the first request, stops a
+// chain that comes back to a request already sent with ErrRedirectLoop,
+// applies the redirect policy of the request, else of the client, and
+// records the redirects followed.
+func (cl *StandardClient) httpClient(r *Request) *http.Client {
+	c := *cl.Client
+	checkRedirect := c.CheckRedirect
+	if r.redirectPolicy != nil {
+		checkRedirect = r.redirectPolicy
+	}
+	maxRedirects := cl.maxRedirects
+	if r.maxRedirects != 0 {
+		maxRedirects = r.maxRedirects
+	}
+	c.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		for _, prev := range via {
+			if prev.Method == req.Method && prev.URL.String() == req.URL.String() {
+				return fmt.Errorf("%w: %s %s", ErrRedirectLoop, req.Method, req.URL)
+			}
+		}
+		if enc := via[0].Header.Get("Accept-Encoding"); cl.acceptEncoding != "" && enc != "" {
+			req.Header.Set("Accept-Encoding", enc)
+		}
+		var err error
+		switch {
+		case checkRedirect != nil:
+			err = checkRedirect(req, via)
+		case maxRedirects < 0:
+			err = http.ErrUseLastResponse
+		case maxRedirects == 0 && len(via) >= 10:
+			err = fmt.Errorf("%w: 10", ErrTooManyRedirects)
+		case maxRedirects > 0 && len(via) > maxRedirects:
+			err = fmt.Errorf("%w: %d", ErrTooManyRedirects, maxRedirects)
+		}
+		if err == nil {
+			r.redirects = append(r.redirects, req.URL.String())
+		}
+		return err
+	}
+	return &c
+}
+
+// WithMaxRedirects makes the requests fail with ErrTooManyRedirects once
+// they followed n redirects, instead of 10. With n = 0 the redirects are
+// not followed, as with WithNoRedirects.
+func (cl *StandardClient) WithMaxRedirects(n int) *StandardClient {
+	cl.maxRedirects = n
+	if n <= 0 {
+		cl.maxRedirects = -1
+	}
+	return cl
+}
+
+// WithNoRedirects returns the redirect responses as they are, to be
+// followed with Response.Follow if needed.
+func (cl *StandardClient) WithNoRedirects() *StandardClient {
+	cl.maxRedirects = -1
+	return cl
+}
+
+// WithBaseURL sets BaseURL, the URL the relative request URIs are resolved
+// against.
+func (cl *StandardClient) WithBaseURL(baseURL string) *StandardClient {
+	cl.BaseURL = baseURL
+	return cl
+}
+
+// resolve resolves a relative uri against BaseURL, absolute URIs and all
+// URIs of a client without BaseURL are returned as is. As usual for URL
+// references, "/v1/users" replaces the path of BaseURL while "users" is
+// appended to a BaseURL path ending with a slash.
+func (cl *StandardClient) resolve(uri string) (string, error) {
+	return resolveURI(cl.BaseURL, uri)
+}
+
+func resolveURI(baseURL, uri string) (string, error) {
+	if baseURL == "" {
+		return uri, nil
+	}
+	ref, err := url.Parse(uri)
+	if err != nil {
+		return "", err
+	}
+	if ref.IsAbs() {
+		return uri, nil
+	}
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return "", err
+	}
+	return base.ResolveReference(ref).String(), nil
+}
+
+// WithStrictContentLength makes Content, Bytes, Json, etc. fail with
+// ErrContentLengthMismatch, with the expected and the actual sizes, when
+// a body ends before the Content-Length the server declared.
+func (cl *StandardClient) WithStrictContentLength() *StandardClient {
+	cl.strictLength = true
+	return cl
+}
+
+// WithRawBodies keeps gzip and deflate response bodies compressed as
+// received, by default they are decompressed for all the readers.
+func (cl *StandardClient) WithRawBodies() *StandardClient {
+	cl.rawBodies = true
+	return cl
+}
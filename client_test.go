@@ -0,0 +1,339 @@
+package www
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newCert returns a self-signed certificate valid only for the names.
+func newCert(t *testing.T, names ...string) (tls.Certificate, *x509.CertPool) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: names[0]},
+		DNSNames:              names,
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: cert}, pool
+}
+
+func TestWithServerName(t *testing.T) {
+
+	cert, pool := newCert(t, "service.internal")
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.TLS.ServerName))
+	}))
+	srv.TLS = &tls.Config{Certificates: []tls.Certificate{cert}}
+	srv.StartTLS()
+	defer srv.Close()
+
+	newClient := func() *StandardClient {
+		return NewClient(&http.Client{
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}},
+		})
+	}
+
+	t.Run("BY IP", func(t *testing.T) {
+		resp := NewRequest(newClient()).Get(srv.URL)
+		if resp.Error() == nil {
+			t.Errorf("the certificate must not be valid for %s", srv.URL)
+		}
+	})
+
+	t.Run("SERVER NAME", func(t *testing.T) {
+		cl := newClient().WithServerName("service.internal")
+		if cl.Error() != nil {
+			t.Fatalf("%v", cl.Error())
+		}
+		resp := NewRequest(cl).Get(srv.URL)
+		if resp.Error() != nil {
+			t.Fatalf("%v", resp.Error())
+		}
+		if got := resp.Text(); got != "service.internal" {
+			t.Errorf("SNI:got %q, want %q", got, "service.internal")
+		}
+	})
+}
+
+func TestClose(t *testing.T) {
+
+	states := make(chan http.ConnState, 10)
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		states <- state
+	}
+	srv.Start()
+	defer srv.Close()
+
+	cl := Pooled()
+	stopped := make(chan struct{})
+	cl.onClose(func() { close(stopped) })
+
+	resp := NewRequest(cl).Get(srv.URL)
+	if resp.Error() != nil {
+		t.Fatalf("%v", resp.Error())
+	}
+	resp.Content()
+
+	wait := func(want http.ConnState) {
+		t.Helper()
+		for {
+			select {
+			case state := <-states:
+				if state == want {
+					return
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatalf("the connection did not become %s", want)
+			}
+		}
+	}
+	wait(http.StateIdle)
+
+	cl.Close()
+	wait(http.StateClosed)
+
+	select {
+	case <-stopped:
+	default:
+		t.Errorf("the background work was not stopped")
+	}
+}
+
+func TestWithHostMapping(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Host + r.URL.Path))
+	}))
+	defer srv.Close()
+
+	cl := NewClient().WithHostMapping(map[string]string{
+		"api.example.com": strings.TrimPrefix(srv.URL, "http://"),
+	})
+	resp := NewRequest(cl).Get("http://api.example.com/v1/users")
+	if resp.Error() != nil {
+		t.Fatalf("%v", resp.Error())
+	}
+	if got := resp.Text(); got != "api.example.com/v1/users" {
+		t.Errorf("got %q, want %q", got, "api.example.com/v1/users")
+	}
+}
+
+type closeRecorder struct {
+	io.Reader
+	closer io.Closer
+	name   string
+	closed *[]string
+}
+
+func (c closeRecorder) Close() error {
+	*c.closed = append(*c.closed, c.name)
+	return c.closer.Close()
+}
+
+func TestWithBodyTransform(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello"))
+	}))
+	defer srv.Close()
+
+	var closed []string
+	upper := func(body io.ReadCloser) io.ReadCloser {
+		data, _ := io.ReadAll(body)
+		return closeRecorder{strings.NewReader(strings.ToUpper(string(data))), body, "upper", &closed}
+	}
+	suffix := func(body io.ReadCloser) io.ReadCloser {
+		return closeRecorder{io.MultiReader(body, strings.NewReader("!")), body, "suffix", &closed}
+	}
+
+	cl := NewClient().WithBodyTransform(upper).WithBodyTransform(suffix)
+	if got := string(NewRequest(cl).Get(srv.URL).Content()); got != "HELLO!" {
+		t.Errorf("got %q, want %q", got, "HELLO!")
+	}
+	if strings.Join(closed, ",") != "suffix,upper" {
+		t.Errorf("closed:got %v, want [suffix upper]", closed)
+	}
+}
+
+func TestProtocolMismatch(t *testing.T) {
+
+	t.Run("HTTP ON HTTPS", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		defer srv.Close()
+
+		resp := NewRequest(NewClient()).Get(strings.Replace(srv.URL, "http://", "https://", 1))
+		if !errors.Is(resp.Error(), ErrProtocolMismatch) {
+			t.Errorf("Error:got %v, want %v", resp.Error(), ErrProtocolMismatch)
+		}
+	})
+
+	t.Run("TLS ON HTTP", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer ln.Close()
+		go func() {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			conn.Read(make([]byte, 1024))
+			// alert: protocol version
+			conn.Write([]byte("\x15\x03\x01\x00\x02\x02\x46"))
+		}()
+
+		resp := NewRequest(NewClient()).Get("http://" + ln.Addr().String())
+		if !errors.Is(resp.Error(), ErrProtocolMismatch) {
+			t.Errorf("Error:got %v, want %v", resp.Error(), ErrProtocolMismatch)
+		}
+	})
+}
+
+func TestWithAcceptEncoding(t *testing.T) {
+
+	gzipped := func(w http.ResponseWriter, s string) {
+		w.Header().Set("Content-Encoding", "gzip")
+		zw := gzip.NewWriter(w)
+		zw.Write([]byte(s))
+		zw.Close()
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept-Encoding") != "gzip" {
+			w.Write([]byte("identity"))
+			return
+		}
+		if r.URL.Path == "/start" {
+			w.Header().Set("Location", "/final")
+			w.WriteHeader(http.StatusFound)
+			gzipped(w, "moved")
+			return
+		}
+		gzipped(w, "hello")
+	}))
+	defer srv.Close()
+
+	resp := NewRequest(NewClient().WithAcceptEncoding()).Get(srv.URL + "/start")
+	if got := resp.Text(); got != "hello" {
+		t.Errorf("got %q, want %q", got, "hello")
+	}
+	if info := resp.Encoding(); !info.ClientDecompressed || info.AcceptEncoding != "gzip" {
+		t.Errorf("Encoding:got %+v", info)
+	}
+}
+
+func TestAcceptEncodingPreference(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Accept-Encoding", r.Header.Get("Accept-Encoding"))
+		w.Header().Set("Content-Encoding", "deflate")
+		zw := zlib.NewWriter(w)
+		zw.Write([]byte("hello"))
+		zw.Close()
+	}))
+	defer srv.Close()
+
+	resp := NewRequest(NewClient().WithAcceptEncoding("deflate", "gzip", "identity")).Get(srv.URL)
+	if got, want := resp.Header.Get("X-Accept-Encoding"), "deflate;q=1.0, gzip;q=0.8, identity;q=0.6"; got != want {
+		t.Errorf("Accept-Encoding:got %q, want %q", got, want)
+	}
+	if got := resp.Text(); got != "hello" {
+		t.Errorf("got %q, want %q", got, "hello")
+	}
+
+	if got, want := qualityList([]string{"gzip", "identity;q=0"}), "gzip;q=1.0, identity;q=0"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	cl := NewClient().WithAcceptEncoding("zstd;q=1.0", "gzip;q=0.8")
+	if err := NewRequest(cl).Get(srv.URL).Error(); !errors.Is(err, ErrorUnsupportedEncoding) {
+		t.Errorf("zstd:got %v, want %v", err, ErrorUnsupportedEncoding)
+	}
+}
+
+func TestBaseURL(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s|%s|%s", r.URL.Path, r.Header.Get("Accept"), r.Header.Get("User-Agent"))
+	}))
+	defer srv.Close()
+
+	cl := NewClient()
+	cl.BaseURL = srv.URL + "/api/"
+	cl.DefaultHeaders = http.Header{"Accept": {"application/json"}, "User-Agent": {"www-test"}}
+
+	tests := []struct {
+		name    string
+		uri     string
+		headers []http.Header
+		want    string
+	}{
+		{"RELATIVE", "v1/users", nil, "/api/v1/users|application/json|www-test"},
+		{"ROOTED", "/v1/users", nil, "/v1/users|application/json|www-test"},
+		{"ABSOLUTE", srv.URL + "/other", nil, "/other|application/json|www-test"},
+		{"OVERRIDE", "v1", []http.Header{{"Accept": {"text/plain"}}}, "/api/v1|text/plain|www-test"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NewRequest(cl).Get(tt.uri, tt.headers...).Text(); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if got, _ := NewClient().resolve("/v1/users"); got != "/v1/users" {
+		t.Errorf("no BaseURL:got %q", got)
+	}
+
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "other %s|%s", r.URL.Path, r.Header.Get("User-Agent"))
+	}))
+	defer other.Close()
+
+	if got, want := NewRequest(cl).BaseURL(other.URL+"/v2/").Get("users").Text(), "other /v2/users|www-test"; got != want {
+		t.Errorf("Request.BaseURL:got %q, want %q", got, want)
+	}
+	if got, want := NewRequest(cl).Get("users").Text(), "/api/users|application/json|www-test"; got != want {
+		t.Errorf("after Request.BaseURL:got %q, want %q", got, want)
+	}
+}
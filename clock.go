@@ -0,0 +1,50 @@
+package www
+
+import (
+	"context"
+	"time"
+)
+
+// Clock is the source of time of a client: durations reported to the
+// metrics callback and the waits between retries and rate limited
+// requests. Tests can inject a fake one with WithClock to advance time
+// instantly, network timeouts always use the real time.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// WithClock replaces the real clock of the client.
+func (cl *StandardClient) WithClock(clock Clock) *StandardClient {
+	cl.clock = clock
+	return cl
+}
+
+func (cl *StandardClient) now() time.Time {
+	if cl.clock == nil {
+		return time.Now()
+	}
+	return cl.clock.Now()
+}
+
+// sleep waits for d on the client clock, an expired ctx stops it early.
+func (cl *StandardClient) sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	var clock Clock = realClock{}
+	if cl.clock != nil {
+		clock = cl.clock
+	}
+	select {
+	case <-clock.After(d):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,65 @@
+package www
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock advances only when asked to wait.
+type fakeClock struct {
+	mu    sync.Mutex
+	now   time.Time
+	slept []time.Duration
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.slept = append(c.slept, d)
+	c.now = c.now.Add(d)
+	ch := make(chan time.Time, 1)
+	ch <- c.now
+	return ch
+}
+
+func TestWithClock(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	var metric RequestMetric
+	cl := NewClient().WithClock(clock).WithMetrics(func(m RequestMetric) { metric = m })
+
+	NewRequest(cl).Get(srv.URL)
+	if metric.Duration != 0 {
+		t.Errorf("Duration:got %v, want 0", metric.Duration)
+	}
+
+	start := time.Now()
+	if err := cl.sleep(context.Background(), time.Hour); err != nil {
+		t.Errorf("%v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("the fake clock slept for real")
+	}
+	if !clock.Now().Equal(time.Unix(3600, 0)) || len(clock.slept) != 1 {
+		t.Errorf("got %v after %v", clock.Now(), clock.slept)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := NewClient().sleep(ctx, time.Hour); err != context.Canceled {
+		t.Errorf("canceled:got %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,88 @@
+package www
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+)
+
+// Clone returns a copy of the request builder that can be sent
+// independently. A buffered body (Json, WithForm, ...) is replayed by
+// every copy, WithSeeker and AttachFile bodies by copies sent one after
+// the other, other streaming bodies can be read only once, so only one of
+// the copies can send them.
+func (r *Request) Clone() *Request {
+	c := *r
+	c.Request = nil
+	c.cookies = append([]*http.Cookie(nil), r.cookies...)
+	c.forwardedFor = append([]string(nil), r.forwardedFor...)
+	c.forwarded = append([]string(nil), r.forwarded...)
+	c.errorPatterns = append([]string(nil), r.errorPatterns...)
+	c.values = append([]contextValue(nil), r.values...)
+	c.headers = r.headers.Clone()
+	if r.generatedKey {
+		c.idempotencyKey = newIdempotencyKey()
+	}
+	if r.pathParams != nil {
+		c.pathParams = make(map[string]string, len(r.pathParams))
+		for name, val := range r.pathParams {
+			c.pathParams[name] = val
+		}
+	}
+	if r.tags != nil {
+		c.tags = make(map[string]string, len(r.tags))
+		for key, val := range r.tags {
+			c.tags[key] = val
+		}
+	}
+
+	if r.getBody == nil {
+		switch body := r.body.(type) {
+		case *bytes.Buffer:
+			c.body = bytes.NewReader(body.Bytes())
+		case sizedReaderAt: // *bytes.Reader, *strings.Reader
+			size := body.Size()
+			c.body = io.NewSectionReader(body, 0, size)
+			c.length = size
+			c.getBody = func() (io.ReadCloser, error) {
+				return io.NopCloser(io.NewSectionReader(body, 0, size)), nil
+			}
+		}
+	}
+
+	return &c
+}
+
+type sizedReaderAt interface {
+	io.ReaderAt
+	Size() int64
+}
+
+// As returns a clone of the request that Send issues with method, so one
+// template serves e.g. a HEAD probe and the following GET:
+//
+//	head := tmpl.As(http.MethodHead).Send(uri)
+//	get := tmpl.As(http.MethodGet).Send(uri)
+//
+// The body replay constraints of Clone apply.
+func (r *Request) As(method string) *Request {
+	c := r.Clone()
+	c.method = method
+	return c
+}
+
+// Prepare sets the method and the URL of a request sent later, e.g. by
+// Batch.Run.
+func (r *Request) Prepare(method, uri string) *Request {
+	r.method, r.uri = method, uri
+	return r
+}
+
+// Send issues the request with the method chosen with As, GET by default.
+func (r *Request) Send(uri string, headers ...http.Header) *Response {
+	method := r.method
+	if method == "" {
+		method = http.MethodGet
+	}
+	return r.Do(method, uri, headers...)
+}
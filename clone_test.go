@@ -0,0 +1,34 @@
+package www
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestAs(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-Echo", fmt.Sprintf("%s %s %s", r.Method, r.URL.RawQuery, body))
+	}))
+	defer srv.Close()
+
+	tmpl := NewRequest(NewClient()).
+		WithQuery(&url.Values{"q": {"go"}}).
+		WithForm(&url.Values{"key": {"value"}})
+
+	for _, want := range []string{"PUT q=go key=value", "POST q=go key=value"} {
+		method := want[:len(want)-len(" q=go key=value")]
+		resp := tmpl.As(method).Send(srv.URL)
+		if resp.Error() != nil {
+			t.Fatalf("%v", resp.Error())
+		}
+		if got := resp.Header.Get("X-Echo"); got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package www
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+var ErrorUnsupportedEncoding = errors.New("unsupported content encoding")
+
+// Compress compresses the request body with gzip (the default) or deflate
+// and sets Content-Encoding. A buffered body (Json, WithForm, ...) is
+// compressed once: Content-Length is the compressed size and redirects
+// and retries resend the same compressed bytes. Other bodies are
+// compressed while they are sent, chunked.
+func (r *Request) Compress(encoding ...string) *Request {
+	r.compress = "gzip"
+	if len(encoding) > 0 {
+		r.compress = encoding[0]
+	}
+	if r.compress != "gzip" && r.compress != "deflate" {
+		r.err = fmt.Errorf("%w: %s", ErrorUnsupportedEncoding, r.compress)
+	}
+	return r
+}
+
+// WithCompression compresses the request bodies with gzip (the default)
+// or deflate as Request.Compress does, Request.NoCompress opts a request
+// out. An unsupported encoding fails every request of the client.
+func (cl *StandardClient) WithCompression(encoding ...string) *StandardClient {
+	compress := "gzip"
+	if len(encoding) > 0 {
+		compress = encoding[0]
+	}
+	if compress != "gzip" && compress != "deflate" {
+		cl.err = fmt.Errorf("%w: %s", ErrorUnsupportedEncoding, compress)
+		return cl
+	}
+	cl.compress = compress
+	return cl
+}
+
+// NoCompress sends the body of the request uncompressed and without
+// Content-Encoding despite the WithCompression default of the client.
+func (r *Request) NoCompress() *Request {
+	r.noCompress = true
+	return r
+}
+
+// encoding returns the compression of the request body, if any.
+func (r *Request) encoding() string {
+	if r.compress != "" {
+		return r.compress
+	}
+	if r.noCompress {
+		return ""
+	}
+	return r.client.compress
+}
+
+func (r *Request) compressor(w io.Writer) io.WriteCloser {
+	if r.encoding() == "deflate" {
+		return zlib.NewWriter(w)
+	}
+	return gzip.NewWriter(w)
+}
+
+// compressBytes returns the compressed copy of a buffered body.
+func (r *Request) compressBytes(body io.Reader) (*bytes.Reader, error) {
+	data, err := ioutil.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	zw := r.compressor(&buf)
+	if _, err = zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err = zw.Close(); err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(buf.Bytes()), nil
+}
+
+// compressStream compresses body while it is read.
+func (r *Request) compressStream(body io.Reader) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		zw := r.compressor(pw)
+		_, err := io.Copy(zw, body)
+		if cerr := zw.Close(); err == nil {
+			err = cerr
+		}
+		pw.CloseWithError(err)
+	}()
+	return struct {
+		io.Reader
+		io.Closer
+	}{pr, closerFunc(func() error {
+		closeReader(body)
+		return pr.Close()
+	})}
+}
+
+type closerFunc func() error
+
+func (fn closerFunc) Close() error {
+	return fn()
+}
+
+func isBuffered(body io.Reader) bool {
+	switch body.(type) {
+	case *bytes.Buffer, *bytes.Reader, *strings.Reader:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,101 @@
+package www
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCompress(t *testing.T) {
+
+	var received [][]byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = append(received, body)
+		if r.URL.Path == "/upload" {
+			http.Redirect(w, r, "/final", http.StatusTemporaryRedirect)
+			return
+		}
+		zr, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		data, _ := io.ReadAll(zr)
+		fmt.Fprintf(w, "%s|%d|%d|%s", r.Header.Get("Content-Encoding"), r.ContentLength, len(body), data)
+	}))
+	defer srv.Close()
+
+	payload := strings.Repeat("compressible ", 100)
+
+	t.Run("BUFFERED REDIRECT", func(t *testing.T) {
+		received = nil
+		got := NewRequest(NewClient()).
+			WithFile(strings.NewReader(payload)).
+			Compress().
+			Post(srv.URL + "/upload").Text()
+
+		if len(received) != 2 || !bytes.Equal(received[0], received[1]) {
+			t.Fatalf("the redirect did not resend the same bytes")
+		}
+		size := len(received[0])
+		if want := fmt.Sprintf("gzip|%d|%d|%s", size, size, payload); got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+		if size >= len(payload) {
+			t.Errorf("the body is not compressed: %d bytes", size)
+		}
+	})
+
+	t.Run("STREAMING", func(t *testing.T) {
+		got := NewRequest(NewClient()).
+			WithFile(io.MultiReader(strings.NewReader(payload))).
+			Compress("gzip").
+			Post(srv.URL).Text()
+		if !strings.HasPrefix(got, "gzip|-1|") || !strings.HasSuffix(got, "|"+payload) {
+			t.Errorf("got %q", got)
+		}
+	})
+
+	t.Run("UNSUPPORTED", func(t *testing.T) {
+		if r := NewRequest(NewClient()).Compress("br"); !errors.Is(r.Error(), ErrorUnsupportedEncoding) {
+			t.Errorf("Error:got %v, want %v", r.Error(), ErrorUnsupportedEncoding)
+		}
+	})
+}
+
+func TestNoCompress(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		fmt.Fprintf(w, "%s|%s", r.Header.Get("Content-Encoding"), body)
+	}))
+	defer srv.Close()
+
+	cl := NewClient().WithCompression()
+
+	got := NewRequest(cl).WithFile(strings.NewReader("tiny")).NoCompress().Post(srv.URL).Text()
+	if want := "|tiny"; got != want {
+		t.Errorf("NoCompress:got %q, want %q", got, want)
+	}
+
+	got = string(NewRequest(cl).WithFile(strings.NewReader("tiny")).Post(srv.URL).Content())
+	if !strings.HasPrefix(got, "gzip|") || strings.HasSuffix(got, "|tiny") {
+		t.Errorf("client default:got %q, want a gzip body", got)
+	}
+
+	br := NewClient().WithCompression("br")
+	if err := br.Error(); !errors.Is(err, ErrorUnsupportedEncoding) {
+		t.Errorf("WithCompression:got %v, want %v", err, ErrorUnsupportedEncoding)
+	}
+	resp := NewRequest(br).WithFile(strings.NewReader("tiny")).Post(srv.URL)
+	if !errors.Is(resp.Error(), ErrorUnsupportedEncoding) || resp.Response != nil {
+		t.Errorf("request of the client:got %v, want %v", resp.Error(), ErrorUnsupportedEncoding)
+	}
+}
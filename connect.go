@@ -0,0 +1,114 @@
+package www
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+)
+
+var (
+	ErrorNoProxy        = errors.New("no proxy is configured for the CONNECT request")
+	ErrorConnectRefused = errors.New("the proxy refused the CONNECT request")
+)
+
+// bufferedConn reads the bytes buffered while reading the response first.
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.r.Read(p)
+}
+
+func (cl *StandardClient) transport() http.RoundTripper {
+	if cl.Transport != nil {
+		return cl.Transport
+	}
+	return http.DefaultTransport
+}
+
+func (cl *StandardClient) dialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	if t, ok := cl.transport().(*http.Transport); ok && t.DialContext != nil {
+		return t.DialContext(ctx, network, addr)
+	}
+	return (&net.Dialer{}).DialContext(ctx, network, addr)
+}
+
+func (cl *StandardClient) proxyURL(target *url.URL) (*url.URL, error) {
+	t, ok := cl.transport().(*http.Transport)
+	if !ok || t.Proxy == nil {
+		return nil, nil
+	}
+	return t.Proxy(&http.Request{URL: target, Header: make(http.Header)})
+}
+
+// Connect asks the proxy of the client transport to open a tunnel to
+// hostport (CONNECT host:port) and returns the tunnel connection.
+// The client timeout bounds only the dial and the CONNECT exchange.
+// The caller owns the connection and must close it, the client does not
+// reuse or track it. When the proxy refuses the tunnel, the connection is
+// closed and the returned Response holds the proxy answer.
+func (r *Request) Connect(hostport string, headers ...http.Header) (net.Conn, *Response) {
+	defer closeReader(r.body)
+
+	if r.err != nil {
+		return nil, &Response{err: r.err}
+	}
+	r.method, r.uri = http.MethodConnect, hostport
+
+	proxy, err := r.client.proxyURL(&url.URL{Scheme: "https", Host: hostport})
+	if err != nil {
+		return nil, &Response{err: err}
+	}
+	if proxy == nil {
+		return nil, &Response{err: ErrorNoProxy}
+	}
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: hostport},
+		Host:   hostport,
+		Header: make(http.Header),
+	}
+	if len(headers) > 0 {
+		for key, val := range headers[0] {
+			req.Header.Set(key, val[0])
+		}
+	}
+	if proxy.User != nil {
+		password, _ := proxy.User.Password()
+		req.Header.Set("Proxy-Authorization", "Basic "+base64.StdEncoding.EncodeToString(
+			[]byte(proxy.User.Username()+":"+password)))
+	}
+	r.Request = req
+
+	conn, br, resp, err := r.client.exchange(proxy, req)
+	if err != nil {
+		return nil, &Response{err: err, request: r}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		// the refusal page is kept, up to 64 KiB from a misbehaving proxy
+		content, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1<<16))
+		resp.Body = ioutil.NopCloser(bytes.NewReader(content))
+		conn.Close()
+		return nil, &Response{
+			Response: resp,
+			err:      fmt.Errorf("%w: %s", ErrorConnectRefused, resp.Status),
+			request:  r,
+		}
+	}
+
+	resp.Body = http.NoBody
+
+	return &bufferedConn{conn, br}, &Response{Response: resp, request: r}
+}
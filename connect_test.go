@@ -0,0 +1,126 @@
+package www
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestConnect(t *testing.T) {
+
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello"))
+	}))
+	defer target.Close()
+
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect || r.Host == "forbidden:443" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(strings.Repeat("x", 1<<20)))
+			return
+		}
+		upstream, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		conn, _, _ := w.(http.Hijacker).Hijack()
+		go func() {
+			io.Copy(upstream, conn)
+			upstream.Close()
+		}()
+		io.Copy(conn, upstream)
+		conn.Close()
+	}))
+	defer proxy.Close()
+
+	proxyURL, _ := url.Parse(proxy.URL)
+	cl := NewClient(&http.Client{
+		Transport: &http.Transport{Proxy: http.ProxyURL(proxyURL)},
+	})
+
+	t.Run("TUNNEL", func(t *testing.T) {
+		conn, resp := NewRequest(cl).Connect(strings.TrimPrefix(target.URL, "http://"))
+		if resp.Error() != nil {
+			t.Fatalf("%v", resp.Error())
+		}
+		defer conn.Close()
+
+		io.WriteString(conn, "GET / HTTP/1.1\r\nHost: target\r\nConnection: close\r\n\r\n")
+		got, err := http.ReadResponse(bufio.NewReader(conn), nil)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		body, _ := io.ReadAll(got.Body)
+		if string(body) != "hello" {
+			t.Errorf("body:got %q, want %q", body, "hello")
+		}
+	})
+
+	t.Run("REFUSED", func(t *testing.T) {
+		conn, resp := NewRequest(cl).Connect("forbidden:443")
+		if conn != nil || !errors.Is(resp.Error(), ErrorConnectRefused) {
+			t.Errorf("Error:got %v, want %v", resp.Error(), ErrorConnectRefused)
+		}
+		if resp.StatusCode != http.StatusForbidden {
+			t.Errorf("StatusCode:got %d, want 403", resp.StatusCode)
+		}
+		if n := len(resp.Content()); n != 1<<16 {
+			t.Errorf("body:got %d bytes, want %d", n, 1<<16)
+		}
+	})
+
+	t.Run("NO PROXY", func(t *testing.T) {
+		_, resp := NewRequest(NewClient(&http.Client{Transport: &http.Transport{}})).
+			Connect("example.com:443")
+		if !errors.Is(resp.Error(), ErrorNoProxy) {
+			t.Errorf("Error:got %v, want %v", resp.Error(), ErrorNoProxy)
+		}
+	})
+}
+
+func TestHijack(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Upgrade") != "echo" || r.Header.Get("Connection") != "Upgrade" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		conn, buf, _ := w.(http.Hijacker).Hijack()
+		defer conn.Close()
+		buf.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: echo\r\nConnection: Upgrade\r\n\r\n")
+		buf.Flush()
+		line, _ := buf.ReadString('\n')
+		buf.WriteString(line)
+		buf.Flush()
+	}))
+	defer srv.Close()
+
+	t.Run("UPGRADE", func(t *testing.T) {
+		conn, rw, err := NewRequest(NewClient()).Hijack(srv.URL, http.Header{"Upgrade": {"echo"}})
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		defer conn.Close()
+
+		rw.WriteString("ping\n")
+		rw.Flush()
+		if line, _ := rw.ReadString('\n'); line != "ping\n" {
+			t.Errorf("got %q, want %q", line, "ping\n")
+		}
+	})
+
+	t.Run("NOT UPGRADED", func(t *testing.T) {
+		_, _, err := NewRequest(NewClient()).Hijack(srv.URL)
+		if !errors.Is(err, ErrorNotUpgraded) {
+			t.Errorf("Error:got %v, want %v", err, ErrorNotUpgraded)
+		}
+	})
+}
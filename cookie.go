@@ -0,0 +1,248 @@
+package www
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StoredCookie is a cookie kept by a CookieStorage with the URL that set
+// it.
+type StoredCookie struct {
+	URL    string
+	Cookie *http.Cookie
+}
+
+// CookieStorage keeps the cookies of the jar of EnableCookieJar across
+// process restarts.
+type CookieStorage interface {
+	Load() ([]StoredCookie, error)
+	Save(cookies []StoredCookie) error
+}
+
+// EnableCookieJar gives the client a cookie jar (net/http/cookiejar), the
+// cookies set by the responses are sent with the following requests as in
+// a browser session. With a storage the jar starts with the stored
+// cookies, expired ones dropped, and stores all of them again whenever a
+// response changes them, so a login survives a restart; a failed save is
+// logged, see SaveCookies.
+func (cl *StandardClient) EnableCookieJar(storage ...CookieStorage) *StandardClient {
+	jar, _ := cookiejar.New(nil)
+	if len(storage) == 0 {
+		cl.Jar = jar
+		return cl
+	}
+	pj := &persistentJar{jar: jar, storage: storage[0], client: cl, cookies: make(map[string]*jarCookie)}
+	if err := pj.load(); err != nil {
+		cl.err = err
+		return cl
+	}
+	cl.Jar = pj
+	return cl
+}
+
+// SaveCookies stores the cookies of a jar enabled with a storage, it does
+// nothing for other jars.
+func (cl *StandardClient) SaveCookies() error {
+	if pj, ok := cl.Jar.(*persistentJar); ok {
+		return pj.save()
+	}
+	return nil
+}
+
+// persistentJar records the cookies the jar accepts, a cookiejar.Jar
+// cannot list them.
+type persistentJar struct {
+	mu      sync.Mutex
+	jar     *cookiejar.Jar
+	storage CookieStorage
+	client  *StandardClient
+	// by domain, path and name as the jar
+	cookies map[string]*jarCookie
+	seq     int
+}
+
+// jarCookie is saved in the order the cookies were first set
+type jarCookie struct {
+	StoredCookie
+	seq int
+}
+
+func (pj *persistentJar) Cookies(u *url.URL) []*http.Cookie {
+	return pj.jar.Cookies(u)
+}
+
+func (pj *persistentJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	pj.jar.SetCookies(u, cookies)
+	if len(cookies) == 0 {
+		return
+	}
+	pj.mu.Lock()
+	now := pj.client.now()
+	origin := (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: u.Path}).String()
+	for _, c := range cookies {
+		c := *c
+		if c.MaxAge > 0 { // relative, kept as Expires
+			c.Expires = now.Add(time.Duration(c.MaxAge) * time.Second)
+			c.MaxAge = 0
+		}
+		key := cookieKey(u, &c)
+		if c.MaxAge < 0 || !c.Expires.IsZero() && !c.Expires.After(now) {
+			delete(pj.cookies, key)
+		} else if pj.accepted(u, &c) {
+			pj.store(key, StoredCookie{URL: origin, Cookie: &c})
+		}
+	}
+	pj.mu.Unlock()
+
+	if err := pj.save(); err != nil {
+		pj.client.logError("saving the cookies: " + err.Error())
+	}
+}
+
+func (pj *persistentJar) load() error {
+	stored, err := pj.storage.Load()
+	if err != nil {
+		return err
+	}
+	now := pj.client.now()
+	for _, sc := range stored {
+		u, err := url.Parse(sc.URL)
+		if err != nil || sc.Cookie == nil {
+			continue
+		}
+		if !sc.Cookie.Expires.IsZero() && !sc.Cookie.Expires.After(now) {
+			continue
+		}
+		pj.jar.SetCookies(u, []*http.Cookie{sc.Cookie})
+		if pj.accepted(u, sc.Cookie) {
+			pj.store(cookieKey(u, sc.Cookie), sc)
+		}
+	}
+	return nil
+}
+
+// store keeps the place of a cookie set again.
+func (pj *persistentJar) store(key string, sc StoredCookie) {
+	if jc, ok := pj.cookies[key]; ok {
+		jc.StoredCookie = sc
+		return
+	}
+	pj.seq++
+	pj.cookies[key] = &jarCookie{sc, pj.seq}
+}
+
+// accepted reports whether the jar sends the cookie back, it drops the
+// cookies of a foreign domain for instance.
+func (pj *persistentJar) accepted(u *url.URL, c *http.Cookie) bool {
+	key := strings.SplitN(cookieKey(u, c), ";", 3)
+	scheme := u.Scheme
+	if c.Secure {
+		scheme = "https"
+	}
+	for _, sent := range pj.jar.Cookies(&url.URL{Scheme: scheme, Host: key[0], Path: key[1]}) {
+		if sent.Name == c.Name && sent.Value == c.Value {
+			return true
+		}
+	}
+	return false
+}
+
+func (pj *persistentJar) save() error {
+	pj.mu.Lock()
+	defer pj.mu.Unlock()
+	now := pj.client.now()
+	kept := make([]*jarCookie, 0, len(pj.cookies))
+	for key, jc := range pj.cookies {
+		if !jc.Cookie.Expires.IsZero() && !jc.Cookie.Expires.After(now) {
+			delete(pj.cookies, key)
+			continue
+		}
+		kept = append(kept, jc)
+	}
+	sort.Slice(kept, func(i, j int) bool {
+		return kept[i].seq < kept[j].seq
+	})
+	cookies := make([]StoredCookie, len(kept))
+	for i, jc := range kept {
+		cookies[i] = jc.StoredCookie
+	}
+	return pj.storage.Save(cookies)
+}
+
+func cookieKey(u *url.URL, c *http.Cookie) string {
+	domain, path := c.Domain, c.Path
+	if domain == "" {
+		domain = u.Hostname()
+	}
+	if path == "" { // the default path of the jar
+		path = "/"
+		if i := strings.LastIndex(u.Path, "/"); i > 0 {
+			path = u.Path[:i]
+		}
+	}
+	return strings.TrimPrefix(domain, ".") + ";" + path + ";" + c.Name
+}
+
+func (cl *StandardClient) logError(msg string) {
+	switch logger := cl.Logger.(type) {
+	case LeveledLogger:
+		logger.Error(msg)
+	case Logger:
+		logger.Printf("%s", msg)
+	}
+}
+
+// FileCookieStorage is a CookieStorage keeping the cookies as JSON in a
+// file, readable by its owner only.
+type FileCookieStorage struct {
+	path string
+}
+
+func NewFileCookieStorage(path string) *FileCookieStorage {
+	return &FileCookieStorage{path: path}
+}
+
+// Load returns no cookies when the file does not exist yet.
+func (s *FileCookieStorage) Load() ([]StoredCookie, error) {
+	data, err := ioutil.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var cookies []StoredCookie
+	err = json.Unmarshal(data, &cookies)
+	return cookies, err
+}
+
+// Save replaces the file atomically.
+func (s *FileCookieStorage) Save(cookies []StoredCookie) error {
+	data, err := json.MarshalIndent(cookies, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
@@ -0,0 +1,97 @@
+package www
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEnableCookieJar(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "s1", Path: "/", MaxAge: 3600})
+			http.SetCookie(w, &http.Cookie{Name: "temp", Value: "t1", Path: "/"})
+			http.SetCookie(w, &http.Cookie{Name: "foreign", Value: "f1", Path: "/", Domain: "example.com"})
+		case "/logout":
+			http.SetCookie(w, &http.Cookie{Name: "session", Path: "/", MaxAge: -1})
+		}
+		for _, c := range r.Cookies() {
+			w.Write([]byte(c.Name + "=" + c.Value + ";"))
+		}
+	}))
+	defer srv.Close()
+
+	t.Run("SESSION", func(t *testing.T) {
+		cl := NewClient().EnableCookieJar()
+		NewRequest(cl).Get(srv.URL + "/login").Text()
+		if got, want := NewRequest(cl).Get(srv.URL+"/me").Text(), "session=s1;temp=t1;"; got != want {
+			t.Errorf("Cookie:got %q, want %q", got, want)
+		}
+	})
+
+	t.Run("PERSISTED", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "cookies.json")
+		cl := NewClient().EnableCookieJar(NewFileCookieStorage(path))
+		NewRequest(cl).Get(srv.URL + "/login").Text()
+
+		stored, err := NewFileCookieStorage(path).Load()
+		var names []string
+		for _, sc := range stored {
+			names = append(names, sc.Cookie.Name)
+		}
+		if got, want := fmt.Sprint(names), "[session temp]"; err != nil || got != want {
+			t.Errorf("stored:got %v, %v, want %v in the order set, the foreign one dropped", got, err, want)
+		}
+
+		restarted := NewClient().EnableCookieJar(NewFileCookieStorage(path))
+		if err := restarted.Error(); err != nil {
+			t.Fatalf("%v", err)
+		}
+		sent := strings.Split(NewRequest(restarted).Get(srv.URL+"/me").Text(), ";")
+		sort.Strings(sent)
+		if got, want := strings.Join(sent, ";"), ";session=s1;temp=t1"; got != want {
+			t.Errorf("restored:got %q, want %q", got, want)
+		}
+		if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+			t.Errorf("file:got %v, %v", info, err)
+		}
+
+		NewRequest(restarted).Get(srv.URL + "/logout").Text()
+		stored, err = NewFileCookieStorage(path).Load()
+		if err != nil || len(stored) != 1 || stored[0].Cookie.Name != "temp" {
+			t.Errorf("after logout:got %+v, %v", stored, err)
+		}
+	})
+
+	t.Run("EXPIRED", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "cookies.json")
+		data, _ := json.Marshal([]StoredCookie{
+			{URL: srv.URL, Cookie: &http.Cookie{Name: "old", Value: "1", Expires: time.Now().Add(-time.Hour)}},
+			{URL: srv.URL, Cookie: &http.Cookie{Name: "new", Value: "2", Expires: time.Now().Add(time.Hour)}},
+		})
+		ioutil.WriteFile(path, data, 0600)
+
+		cl := NewClient().EnableCookieJar(NewFileCookieStorage(path))
+		if got, want := NewRequest(cl).Get(srv.URL+"/me").Text(), "new=2;"; got != want {
+			t.Errorf("Cookie:got %q, want %q", got, want)
+		}
+	})
+
+	t.Run("BAD FILE", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "cookies.json")
+		ioutil.WriteFile(path, []byte("not json"), 0600)
+		if err := NewClient().EnableCookieJar(NewFileCookieStorage(path)).Error(); err == nil {
+			t.Errorf("got %v, want an error", err)
+		}
+	})
+}
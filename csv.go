@@ -0,0 +1,89 @@
+package www
+
+import (
+	"encoding/csv"
+	"io"
+)
+
+type csvConfig struct {
+	comma      rune
+	skipHeader bool
+	header     func([]string)
+}
+
+// CSVOption configures the CSV decoding of a response.
+type CSVOption func(*csvConfig)
+
+// CSVDelimiter sets the field delimiter, a comma by default.
+func CSVDelimiter(comma rune) CSVOption {
+	return func(c *csvConfig) {
+		c.comma = comma
+	}
+}
+
+// CSVHeader drops the first row, fn (if not nil) receives it.
+func CSVHeader(fn func(header []string)) CSVOption {
+	return func(c *csvConfig) {
+		c.skipHeader = true
+		c.header = fn
+	}
+}
+
+// CSV reads all the rows of a CSV body and closes it.
+// Quoted fields may contain delimiters and newlines.
+func (resp *Response) CSV(opts ...CSVOption) ([][]string, error) {
+	var rows [][]string
+	err := resp.CSVRows(func(row []string) error {
+		rows = append(rows, row)
+		return nil
+	}, opts...)
+	return rows, err
+}
+
+// CSVRows streams the rows of a CSV body to fn and closes the body.
+// It stops at the first error of fn or when the request context is done.
+func (resp *Response) CSVRows(fn func([]string) error, opts ...CSVOption) error {
+	if resp.err != nil {
+		return resp.err
+	}
+	if resp.Response == nil {
+		return ErrorNoResponse
+	}
+	defer resp.Body.Close()
+
+	config := csvConfig{comma: ','}
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	body, err := resp.bodyReader()
+	if err != nil {
+		return err
+	}
+	reader := csv.NewReader(body)
+	reader.Comma = config.comma
+	reader.FieldsPerRecord = -1
+
+	ctx := resp.Request.Context()
+	for first := true; ; first = false {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		row, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if first && config.skipHeader {
+			if config.header != nil {
+				config.header(row)
+			}
+			continue
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package www
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestCSV(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/csv")
+		w.Write([]byte("id;note\n1;\"multi\nline\"\n2;\"a;b\"\n"))
+	}))
+	defer srv.Close()
+
+	var header []string
+	rows, err := NewRequest(NewClient()).Get(srv.URL).CSV(
+		CSVDelimiter(';'),
+		CSVHeader(func(h []string) { header = h }),
+	)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	want := [][]string{{"1", "multi\nline"}, {"2", "a;b"}}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("rows:got %q, want %q", rows, want)
+	}
+	if !reflect.DeepEqual(header, []string{"id", "note"}) {
+		t.Errorf("header:got %q", header)
+	}
+}
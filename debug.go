@@ -0,0 +1,171 @@
+package www
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// debugPreview is the number of bytes of a request body that are logged
+const debugPreview = 512
+
+// previewBody keeps the start of the request body as it is sent, the body
+// is read by the transport while the exchange may already be logged.
+type previewBody struct {
+	io.ReadCloser
+	mu      sync.Mutex
+	preview []byte
+	sent    int64
+}
+
+func (b *previewBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.mu.Lock()
+	if room := debugPreview - len(b.preview); room > 0 {
+		if room > n {
+			room = n
+		}
+		b.preview = append(b.preview, p[:room]...)
+	}
+	b.sent += int64(n)
+	b.mu.Unlock()
+	return n, err
+}
+
+// WithDebug logs every attempt with the Logger of the client (with Debug
+// for a LeveledLogger): the method, URL and headers of the request, the
+// start of the body as it was sent, the status and headers of the response and
+// the time spent, split into the DNS lookup, connect, TLS handshake and
+// first byte, with the Operation of the request when it has one. The
+// credentials headers are replaced by Redacted.
+func (cl *StandardClient) WithDebug() *StandardClient {
+	cl.debug = true
+	return cl
+}
+
+// debugTimings is set by the trace hooks, that may run concurrently
+type debugTimings struct {
+	mu                                      sync.Mutex
+	start, dnsStart, connectStart, tlsStart time.Time
+
+	dns, connect, tls, firstByte time.Duration
+}
+
+func (t *debugTimings) set(fn func()) {
+	t.mu.Lock()
+	fn()
+	t.mu.Unlock()
+}
+
+// debugTrace adds the timing hooks to the request, the returned function
+// logs the exchange.
+func (r *Request) debugTrace() func(*http.Response, error) {
+	now := r.client.now
+	t := &debugTimings{start: now()}
+	r.Request = r.Request.WithContext(httptrace.WithClientTrace(r.Request.Context(), &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.set(func() { t.dnsStart = now() })
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.set(func() { t.dns = now().Sub(t.dnsStart) })
+		},
+		ConnectStart: func(string, string) {
+			t.set(func() { t.connectStart = now() })
+		},
+		ConnectDone: func(string, string, error) {
+			t.set(func() { t.connect = now().Sub(t.connectStart) })
+		},
+		TLSHandshakeStart: func() {
+			t.set(func() { t.tlsStart = now() })
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.set(func() { t.tls = now().Sub(t.tlsStart) })
+		},
+		GotFirstResponseByte: func() {
+			t.set(func() { t.firstByte = now().Sub(t.start) })
+		},
+	}))
+	var body *previewBody
+	if r.Request.Body != nil && r.Request.Body != http.NoBody {
+		body = &previewBody{ReadCloser: r.Request.Body}
+		r.Request.Body = body
+	}
+	req := r.Request
+	operation := ""
+	if r.operation != "" {
+		operation = " (" + r.operation + ")"
+	}
+
+	return func(resp *http.Response, err error) {
+		var b strings.Builder
+		fmt.Fprintf(&b, "> %s %s%s\n", req.Method, req.URL, operation)
+		writeHeaders(&b, "> ", req.Header)
+		writeBodyPreview(&b, req, body)
+		if err != nil {
+			fmt.Fprintf(&b, "< %v\n", err)
+		} else {
+			fmt.Fprintf(&b, "< %s %s\n", resp.Proto, resp.Status)
+			writeHeaders(&b, "< ", resp.Header)
+		}
+		t.mu.Lock()
+		fmt.Fprintf(&b, "* %v (dns %v, connect %v, tls %v, first byte %v)%s",
+			now().Sub(t.start), t.dns, t.connect, t.tls, t.firstByte, operation)
+		t.mu.Unlock()
+		r.client.debugf(b.String())
+	}
+}
+
+func writeHeaders(w io.Writer, prefix string, header http.Header) {
+	keys := make([]string, 0, len(header))
+	for key := range header {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := strings.Join(header[key], ", ")
+		for _, sensitive := range sensitiveHeaders {
+			if key == sensitive {
+				value = Redacted
+			}
+		}
+		fmt.Fprintf(w, "%s%s: %s\n", prefix, key, value)
+	}
+}
+
+// writeBodyPreview logs the bytes of the body read by the transport, the
+// body is never read again for the log.
+func writeBodyPreview(w io.Writer, req *http.Request, body *previewBody) {
+	if body == nil {
+		return
+	}
+	if encoding := req.Header.Get("Content-Encoding"); encoding != "" {
+		fmt.Fprintf(w, ">\n> [%d bytes, %s]\n", req.ContentLength, encoding)
+		return
+	}
+
+	body.mu.Lock()
+	defer body.mu.Unlock()
+	fmt.Fprintf(w, ">\n> %s", body.preview)
+	switch {
+	case req.ContentLength > int64(len(body.preview)):
+		fmt.Fprintf(w, "... [%d bytes]", req.ContentLength)
+	case req.ContentLength < 0 && body.sent > int64(len(body.preview)):
+		fmt.Fprintf(w, "... [streamed body]")
+	}
+	fmt.Fprintln(w)
+}
+
+func (cl *StandardClient) debugf(msg string) {
+	switch logger := cl.Logger.(type) {
+	case LeveledLogger:
+		logger.Debug(msg)
+	case Logger:
+		logger.Printf("%s", msg)
+	}
+}
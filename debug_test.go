@@ -0,0 +1,72 @@
+package www
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type logRecorder struct {
+	lines []string
+}
+
+func (l *logRecorder) Printf(format string, args ...interface{}) {
+	l.lines = append(l.lines, fmt.Sprintf(format, args...))
+}
+
+func TestDebug(t *testing.T) {
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Id", "7")
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+
+	logger := &logRecorder{}
+	cl := NewClient().WithTransport(srv.Client().Transport).WithLogger(logger).WithDebug()
+	NewRequest(cl).
+		WithBearerToken("secret").
+		Json(map[string]string{"name": "www"}).
+		Operation("createUser").
+		Post(srv.URL + "/users")
+
+	if len(logger.lines) != 1 {
+		t.Fatalf("logged:got %d exchanges, want 1", len(logger.lines))
+	}
+	got := logger.lines[0]
+	for _, want := range []string{
+		"> POST " + srv.URL + "/users (createUser)\n",
+		"> Authorization: " + Redacted + "\n",
+		`> {"name":"www"}` + "\n",
+		"< HTTP/1.1 201 Created\n",
+		"< X-Id: 7\n",
+		"tls ",
+		") (createUser)",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("log:got %q, want it to contain %q", got, want)
+		}
+	}
+	if strings.Contains(got, "secret") {
+		t.Errorf("log:got %q, the token is not redacted", got)
+	}
+
+	// the body is previewed as it is sent, not opened again
+	logger.lines = nil
+	form := NewMultipartForm().AddField("note", strings.Repeat("x", 1<<10))
+	NewRequest(cl).WithMultipart(form).Post(srv.URL + "/notes")
+	if len(logger.lines) != 1 {
+		t.Fatalf("multipart:got %d exchanges, want 1", len(logger.lines))
+	}
+	if got := logger.lines[0]; !strings.Contains(got, "> --") || !strings.Contains(got, "xx... [streamed body]\n") {
+		t.Errorf("multipart:got %q", got)
+	}
+
+	logger.lines = nil
+	NewRequest(NewClient().WithLogger(logger)).Get(srv.URL)
+	if len(logger.lines) != 0 {
+		t.Errorf("without WithDebug:got %q", logger.lines)
+	}
+}
@@ -0,0 +1,122 @@
+package www
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// XML decodes the XML body into v as Json does for JSON: the body is read
+// once and kept, a 4xx or 5xx response fails with an *HTTPError and a
+// body that is not XML (application/xml, text/xml or a +xml type) with
+// ErrorUnexpectedContentType. The body is converted to UTF-8 first, the
+// encoding of the XML declaration is then ignored.
+func (resp *Response) XML(v interface{}) error {
+	if resp.err != nil {
+		return resp.err
+	}
+	if resp.Response == nil {
+		return ErrorNoResponse
+	}
+	if resp.content == nil {
+		resp.content = resp.readAll(true)
+		if resp.err != nil {
+			return resp.err
+		}
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return resp.statusError()
+	}
+	if mediaType := resp.mediaType(); !isXML(mediaType) {
+		return fmt.Errorf("%w: %q, want XML", ErrorUnexpectedContentType, mediaType)
+	}
+
+	decoder := xml.NewDecoder(bytes.NewReader(resp.content))
+	decoder.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+	return decoder.Decode(v)
+}
+
+// Into decodes the body into v with XML for an XML Content-Type and with
+// Json otherwise.
+func (resp *Response) Into(v interface{}) error {
+	if resp.err == nil && resp.Response != nil && isXML(resp.mediaType()) {
+		return resp.XML(v)
+	}
+	return resp.Json(v)
+}
+
+// String returns the body converted to UTF-8 as Text does, with the error
+// that stopped reading it.
+func (resp *Response) String() (string, error) {
+	if resp.err != nil {
+		return "", resp.err
+	}
+	if resp.Response == nil {
+		return "", ErrorNoResponse
+	}
+	if resp.content == nil {
+		resp.content = resp.readAll(true)
+		if resp.err != nil {
+			return "", resp.err
+		}
+	}
+
+	return string(resp.content), nil
+}
+
+// SaveToFile writes the (decompressed) body to the file at path, streamed
+// unless it was read already, and closes it. The file is removed when
+// the body cannot be read to the end.
+func (resp *Response) SaveToFile(path string) error {
+	if resp.err != nil {
+		return resp.err
+	}
+	if resp.Response == nil {
+		return ErrorNoResponse
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if resp.content != nil {
+		_, err = file.Write(resp.content)
+	} else {
+		err = resp.writeBody(file)
+	}
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path)
+	}
+	return err
+}
+
+func (resp *Response) writeBody(w io.Writer) error {
+	defer resp.Body.Close()
+
+	reader, err := resp.bodyReader()
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, reader)
+	return err
+}
+
+func (resp *Response) mediaType() string {
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	return mediaType
+}
+
+func isXML(mediaType string) bool {
+	return mediaType == "application/xml" || mediaType == "text/xml" || strings.HasSuffix(mediaType, "+xml")
+}
@@ -0,0 +1,85 @@
+package www
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDecode(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/xml":
+			w.Header().Set("Content-Type", "application/atom+xml")
+			w.Write([]byte(`<?xml version="1.0" encoding="windows-1251"?><item><name>www</name></item>`))
+		case "/json":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"name":"www"}`))
+		case "/error":
+			w.Header().Set("Content-Type", "application/xml")
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.Write([]byte("plain"))
+		}
+	}))
+	defer srv.Close()
+
+	type item struct {
+		Name string `xml:"name" json:"name"`
+	}
+
+	t.Run("XML", func(t *testing.T) {
+		var v item
+		if err := NewRequest(NewClient()).Get(srv.URL + "/xml").XML(&v); err != nil || v.Name != "www" {
+			t.Errorf("XML:got %+v, %v", v, err)
+		}
+		if err := NewRequest(NewClient()).Get(srv.URL + "/json").XML(&v); !errors.Is(err, ErrorUnexpectedContentType) {
+			t.Errorf("JSON body:got %v, want %v", err, ErrorUnexpectedContentType)
+		}
+		var httpErr *HTTPError
+		if err := NewRequest(NewClient()).Get(srv.URL + "/error").XML(&v); !errors.As(err, &httpErr) {
+			t.Errorf("404:got %v, want an *HTTPError", err)
+		}
+	})
+
+	t.Run("INTO", func(t *testing.T) {
+		for _, path := range []string{"/xml", "/json"} {
+			var v item
+			if err := NewRequest(NewClient()).Get(srv.URL + path).Into(&v); err != nil || v.Name != "www" {
+				t.Errorf("%s:got %+v, %v", path, v, err)
+			}
+		}
+	})
+
+	t.Run("STRING", func(t *testing.T) {
+		if got, err := NewRequest(NewClient()).Get(srv.URL).String(); got != "plain" || err != nil {
+			t.Errorf("String:got %q, %v", got, err)
+		}
+		if _, err := NewRequest(NewClient()).Get("http://127.0.0.1:1").String(); err == nil {
+			t.Errorf("String:got no error for a failed request")
+		}
+	})
+
+	t.Run("SAVE", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "body")
+		if err := NewRequest(NewClient()).Get(srv.URL).SaveToFile(path); err != nil {
+			t.Fatalf("SaveToFile:got %v", err)
+		}
+		if data, _ := os.ReadFile(path); string(data) != "plain" {
+			t.Errorf("file:got %q, want %q", data, "plain")
+		}
+
+		path = filepath.Join(t.TempDir(), "failed")
+		limited := NewClient().WithMaxResponseSize(2)
+		if err := NewRequest(limited).Get(srv.URL).SaveToFile(path); !errors.Is(err, ErrBodyTooLarge) {
+			t.Errorf("too large:got %v, want %v", err, ErrBodyTooLarge)
+		}
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("the partial file was kept")
+		}
+	})
+}
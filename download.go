@@ -0,0 +1,153 @@
+package www
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// DownloadOption configures Download and DownloadFile.
+type DownloadOption func(*downloadConfig)
+
+type downloadConfig struct {
+	progress func(received, total int64)
+}
+
+// DownloadProgress calls fn after every chunk written with the bytes
+// received so far and the size of the body, -1 when it is not known.
+// A resumed DownloadFile counts the bytes of the file in both.
+func DownloadProgress(fn func(received, total int64)) DownloadOption {
+	return func(c *downloadConfig) {
+		c.progress = fn
+	}
+}
+
+// Stream returns the (decompressed) body without buffering it, limited
+// to the maximum response size of the client. The caller must close it.
+func (resp *Response) Stream() (io.ReadCloser, error) {
+	if resp.err != nil {
+		return nil, resp.err
+	}
+	if resp.Response == nil {
+		return nil, ErrorNoResponse
+	}
+
+	reader, err := resp.bodyReader()
+	if err != nil {
+		return nil, err
+	}
+	return &readCloser{reader, resp.Body}, nil
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// Download copies the body to w without buffering it and closes it, it
+// returns the number of bytes written. It stops when the request context
+// is done.
+func (resp *Response) Download(w io.Writer, opts ...DownloadOption) (int64, error) {
+	return resp.download(w, 0, opts)
+}
+
+func (resp *Response) download(w io.Writer, offset int64, opts []DownloadOption) (int64, error) {
+	body, err := resp.Stream()
+	if err != nil {
+		return 0, err
+	}
+	defer body.Close()
+
+	var config downloadConfig
+	for _, opt := range opts {
+		opt(&config)
+	}
+	total := int64(-1)
+	if resp.ContentLength >= 0 && !resp.decompressed && !resp.Uncompressed {
+		total = offset + resp.ContentLength
+	}
+
+	var written int64
+	buf := make([]byte, 32<<10)
+	for {
+		if resp.request != nil {
+			if err := resp.request.context().Err(); err != nil {
+				return written, err
+			}
+		}
+		n, rerr := body.Read(buf)
+		if n > 0 {
+			if _, err := w.Write(buf[:n]); err != nil {
+				return written, err
+			}
+			written += int64(n)
+			if config.progress != nil {
+				config.progress(offset+written, total)
+			}
+		}
+		if rerr == io.EOF {
+			return written, nil
+		}
+		if rerr != nil {
+			return written, rerr
+		}
+	}
+}
+
+// DownloadFile downloads uri to the file at path with GET. When the file
+// exists, the rest of the body is asked for with a Range header and
+// appended; a server answering with the whole body (200) makes it start
+// over and a 416 means the file is complete. Other statuses fail with an
+// *HTTPError and leave the file as it was.
+func (r *Request) DownloadFile(uri, path string, opts ...DownloadOption) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY, 0o666)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	offset := info.Size()
+	if offset > 0 {
+		r.WithHeader("Range", "bytes="+strconv.FormatInt(offset, 10)+"-")
+	}
+
+	resp := r.Get(uri)
+	if resp.err != nil {
+		return resp.err
+	}
+	switch {
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && offset > 0:
+		resp.Body.Close()
+		return nil
+	case resp.StatusCode == http.StatusPartialContent && offset > 0:
+		if !strings.HasPrefix(resp.Header.Get("Content-Range"), fmt.Sprintf("bytes %d-", offset)) {
+			resp.Body.Close()
+			return fmt.Errorf("the server cannot resume at byte %d: %s", offset, resp.Header.Get("Content-Range"))
+		}
+	case resp.StatusCode == http.StatusOK:
+		offset = 0
+	default:
+		resp.Body.Close()
+		return resp.statusError()
+	}
+
+	if err := file.Truncate(offset); err != nil {
+		resp.Body.Close()
+		return err
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		resp.Body.Close()
+		return err
+	}
+	if _, err := resp.download(file, offset, opts); err != nil {
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,112 @@
+package www
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDownload(t *testing.T) {
+
+	content := strings.Repeat("0123456789", 10<<10)
+	var ranges []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		http.ServeContent(w, r, "data", time.Unix(0, 0), strings.NewReader(content))
+	}))
+	defer srv.Close()
+
+	t.Run("STREAM", func(t *testing.T) {
+		body, err := NewRequest(NewClient()).Get(srv.URL).Stream()
+		if err != nil {
+			t.Fatalf("Stream:got %v", err)
+		}
+		data, _ := io.ReadAll(body)
+		body.Close()
+		if string(data) != content {
+			t.Errorf("Stream:got %d bytes, want %d", len(data), len(content))
+		}
+	})
+
+	t.Run("PROGRESS", func(t *testing.T) {
+		var buf bytes.Buffer
+		var last, total int64
+		n, err := NewRequest(NewClient()).Get(srv.URL).Download(&buf, DownloadProgress(func(received, size int64) {
+			last, total = received, size
+		}))
+		if err != nil || n != int64(len(content)) || buf.String() != content {
+			t.Errorf("Download:got %d bytes, %v", n, err)
+		}
+		if last != int64(len(content)) || total != int64(len(content)) {
+			t.Errorf("progress:got %d/%d, want %d/%d", last, total, len(content), len(content))
+		}
+	})
+
+	t.Run("CANCEL", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		_, err := NewRequest(NewClient()).WithContext(ctx).Get(srv.URL).
+			Download(io.Discard, DownloadProgress(func(int64, int64) { cancel() }))
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Error:got %v, want %v", err, context.Canceled)
+		}
+	})
+
+	t.Run("FILE", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "data")
+		for _, test := range []struct {
+			name    string
+			partial int
+			want    string
+		}{
+			{"NEW", -1, ""},
+			{"RESUMED", 1000, "bytes=1000-"},
+			{"COMPLETE", len(content), "bytes=102400-"},
+		} {
+			if test.partial >= 0 {
+				os.WriteFile(path, []byte(content[:test.partial]), 0o666)
+			}
+			ranges = nil
+			var first int64 = -1
+			err := NewRequest(NewClient()).DownloadFile(srv.URL, path, DownloadProgress(func(received, total int64) {
+				if first < 0 {
+					first = received
+				}
+			}))
+			data, _ := os.ReadFile(path)
+			if err != nil || string(data) != content {
+				t.Errorf("%s:got %d bytes, %v", test.name, len(data), err)
+			}
+			if len(ranges) != 1 || ranges[0] != test.want {
+				t.Errorf("%s:Range got %q, want %q", test.name, ranges, test.want)
+			}
+			if test.name == "RESUMED" && first <= 1000 {
+				t.Errorf("%s:progress got %d first, want the file size counted", test.name, first)
+			}
+			os.Remove(path)
+		}
+	})
+
+	t.Run("FILE ERROR", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "data")
+		os.WriteFile(path, []byte("kept"), 0o666)
+		notFound := httptest.NewServer(http.NotFoundHandler())
+		defer notFound.Close()
+
+		var httpErr *HTTPError
+		if err := NewRequest(NewClient()).DownloadFile(notFound.URL, path); !errors.As(err, &httpErr) {
+			t.Errorf("404:got %v, want an *HTTPError", err)
+		}
+		if data, _ := os.ReadFile(path); string(data) != "kept" {
+			t.Errorf("file:got %q, want %q", data, "kept")
+		}
+	})
+}
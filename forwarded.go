@@ -0,0 +1,69 @@
+package www
+
+import (
+	"strings"
+)
+
+// ForwardedElement is one hop of the RFC 7239 Forwarded header,
+// empty parameters are omitted.
+type ForwardedElement struct {
+	For   string
+	By    string
+	Host  string
+	Proto string
+}
+
+func (e ForwardedElement) String() string {
+	var pairs []string
+	for _, p := range [][2]string{{"for", e.For}, {"by", e.By}, {"host", e.Host}, {"proto", e.Proto}} {
+		if p[1] != "" {
+			pairs = append(pairs, p[0]+"="+forwardedValue(p[1]))
+		}
+	}
+	return strings.Join(pairs, ";")
+}
+
+// a value that is not a token (IPv6 addresses, host:port) is quoted
+func forwardedValue(v string) string {
+	if strings.IndexFunc(v, func(c rune) bool {
+		return !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+			strings.ContainsRune("!#$%&'*+-.^_`|~", c))
+	}) < 0 {
+		return v
+	}
+	if strings.Count(v, ":") > 1 && !strings.HasPrefix(v, "[") {
+		v = "[" + v + "]" // IPv6 address
+	}
+	return `"` + escapeQuotes(v) + `"`
+}
+
+// ForwardedFor appends the client addresses to X-Forwarded-For, an
+// existing value (e.g. passed with the headers of Get) is kept in front,
+// so the header lists the hops from the original client onwards.
+func (r *Request) ForwardedFor(ips ...string) *Request {
+	r.forwardedFor = append(r.forwardedFor, ips...)
+	return r
+}
+
+// Forwarded appends hops to the RFC 7239 Forwarded header the same way.
+func (r *Request) Forwarded(elements ...ForwardedElement) *Request {
+	for _, e := range elements {
+		r.forwarded = append(r.forwarded, e.String())
+	}
+	return r
+}
+
+func (r *Request) prepareForwarded() {
+	appendHeader := func(key string, values []string) {
+		if len(values) == 0 {
+			return
+		}
+		value := strings.Join(values, ", ")
+		if prev := r.Request.Header.Get(key); prev != "" {
+			value = prev + ", " + value
+		}
+		r.Request.Header.Set(key, value)
+	}
+	appendHeader("X-Forwarded-For", r.forwardedFor)
+	appendHeader("Forwarded", r.forwarded)
+}
@@ -0,0 +1,32 @@
+package www
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestForwarded(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Got-For", r.Header.Get("X-Forwarded-For"))
+		w.Header().Set("X-Got-Forwarded", r.Header.Get("Forwarded"))
+	}))
+	defer srv.Close()
+
+	resp := NewRequest(NewClient()).
+		ForwardedFor("198.51.100.17").
+		Forwarded(
+			ForwardedElement{For: "192.0.2.60", Proto: "http", By: "203.0.113.43"},
+			ForwardedElement{For: "2001:db8:cafe::17"},
+		).
+		Get(srv.URL, http.Header{"X-Forwarded-For": {"203.0.113.195, 70.41.3.18"}})
+
+	if got, want := resp.Header.Get("X-Got-For"), "203.0.113.195, 70.41.3.18, 198.51.100.17"; got != want {
+		t.Errorf("X-Forwarded-For:got %q, want %q", got, want)
+	}
+	if got, want := resp.Header.Get("X-Got-Forwarded"),
+		`for=192.0.2.60;by=203.0.113.43;proto=http, for="[2001:db8:cafe::17]"`; got != want {
+		t.Errorf("Forwarded:got %q, want %q", got, want)
+	}
+}
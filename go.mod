@@ -1,13 +1,12 @@
 module github.com/GarryGaller/go-www
 
-go 1.17
+go 1.20
 
 require (
+	github.com/andybalholm/brotli v1.0.6
 	github.com/hashicorp/go-cleanhttp v0.5.2
 	github.com/softlandia/cpd v0.0.0-20210117083209-2413526f2815
+	golang.org/x/text v0.3.6
 )
 
-require (
-	github.com/stretchr/testify v1.7.0 // indirect
-	golang.org/x/text v0.3.6 // indirect
-)
+require github.com/stretchr/testify v1.7.0 // indirect
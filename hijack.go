@@ -0,0 +1,125 @@
+package www
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+var ErrorNotUpgraded = errors.New("the server did not switch protocols")
+
+// exchange sends req over a new connection to the host of u, outside of
+// the transport pool, and reads the response head. The connection is left
+// open and the reader holds the bytes buffered after the head. The client
+// timeout bounds the dial and the exchange only.
+func (cl *StandardClient) exchange(u *url.URL, req *http.Request) (net.Conn, *bufio.Reader, *http.Response, error) {
+	ctx := req.Context()
+	if cl.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cl.Timeout)
+		defer cancel()
+	}
+
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), "80")
+		if u.Scheme == "https" || u.Scheme == "wss" {
+			addr = net.JoinHostPort(u.Hostname(), "443")
+		}
+	}
+
+	conn, err := cl.dialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if u.Scheme == "https" || u.Scheme == "wss" {
+		config := &tls.Config{}
+		if t, ok := cl.transport().(*http.Transport); ok && t.TLSClientConfig != nil {
+			config = t.TLSClientConfig.Clone()
+		}
+		if config.ServerName == "" {
+			config.ServerName = u.Hostname()
+		}
+		// no HTTP/2, the connection is handed over raw
+		config.NextProtos = []string{"http/1.1"}
+		tlsConn := tls.Client(conn, config)
+		if err = tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, nil, nil, err
+		}
+		conn = tlsConn
+	}
+
+	if err = req.Write(conn); err != nil {
+		conn.Close()
+		return nil, nil, nil, err
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		conn.Close()
+		return nil, nil, nil, err
+	}
+
+	conn.SetDeadline(time.Time{})
+	return conn, br, resp, nil
+}
+
+// Hijack sends the request to uri over a dedicated connection and, when
+// the server answers 101 Switching Protocols, returns that connection for
+// a custom protocol (set the Upgrade header with headers). The method is
+// the one chosen with As, GET by default.
+// The ownership of the connection passes to the caller: the client never
+// reuses it and the caller must close it. Reads must go through the
+// returned ReadWriter, it holds the bytes received after the 101 head.
+func (r *Request) Hijack(uri string, headers ...http.Header) (net.Conn, *bufio.ReadWriter, error) {
+	defer closeReader(r.body)
+
+	if r.err != nil {
+		return nil, nil, r.err
+	}
+	method := r.method
+	if method == "" {
+		method = http.MethodGet
+	}
+	r.method, r.uri = method, uri
+
+	r.prepareRequest(method, uri, headers...)
+	if r.err != nil {
+		return nil, nil, r.err
+	}
+	r.prepareCookies()
+	if r.Request.Header.Get("Upgrade") != "" && r.Request.Header.Get("Connection") == "" {
+		r.Request.Header.Set("Connection", "Upgrade")
+	}
+	if r.sign != nil {
+		if r.signBody(); r.err != nil {
+			return nil, nil, r.err
+		}
+	}
+
+	conn, br, resp, err := r.client.exchange(r.Request.URL, r.Request)
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 1<<16))
+		conn.Close()
+		return nil, nil, fmt.Errorf("%w: %s", ErrorNotUpgraded, resp.Status)
+	}
+
+	return conn, bufio.NewReadWriter(br, bufio.NewWriter(conn)), nil
+}
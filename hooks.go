@@ -0,0 +1,44 @@
+package www
+
+// OnBeforeRequest adds a hook run by Do (Get, Post, ...) for every
+// request of the client once it is prepared, the http.Request embedded in
+// r holds its final URL and headers, which the hook may change (Sign sees
+// them). An error fails the request before it is sent. The hooks run in
+// the order they were added.
+func (cl *StandardClient) OnBeforeRequest(fn func(r *Request) error) *StandardClient {
+	cl.beforeRequest = append(cl.beforeRequest, fn)
+	return cl
+}
+
+// OnAfterResponse adds a hook run by Do for every response of the client,
+// failed ones included (see resp.Error), before it is returned. An error
+// closes the body and becomes the error of the response, the next hooks
+// are not run.
+func (cl *StandardClient) OnAfterResponse(fn func(r *Request, resp *Response) error) *StandardClient {
+	cl.afterResponse = append(cl.afterResponse, fn)
+	return cl
+}
+
+func (r *Request) runBeforeRequest() {
+	if r.authenticate(); r.err != nil {
+		return
+	}
+	for _, hook := range r.client.beforeRequest {
+		if err := hook(r); err != nil {
+			r.err = err
+			return
+		}
+	}
+}
+
+func (r *Request) runAfterResponse(resp *Response) {
+	for _, hook := range r.client.afterResponse {
+		if err := hook(r, resp); err != nil {
+			if resp.Response != nil {
+				resp.Body.Close()
+			}
+			resp.err = err
+			return
+		}
+	}
+}
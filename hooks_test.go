@@ -0,0 +1,71 @@
+package www
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHooks(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer srv.Close()
+
+	var calls []string
+	cl := NewClient().
+		OnBeforeRequest(func(r *Request) error {
+			calls = append(calls, "before "+r.Method)
+			r.Header.Set("Authorization", "Bearer token")
+			return nil
+		}).
+		OnAfterResponse(func(r *Request, resp *Response) error {
+			calls = append(calls, "after "+resp.Status)
+			return nil
+		})
+
+	t.Run("CHAIN", func(t *testing.T) {
+		calls = nil
+		var signed string
+		resp := NewRequest(cl).
+			Sign(func(req *http.Request, body []byte) error {
+				signed = req.Header.Get("Authorization")
+				return nil
+			}).
+			Get(srv.URL)
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("status:got %d, want 200", resp.StatusCode)
+		}
+		if len(calls) != 2 || calls[0] != "before GET" || calls[1] != "after 200 OK" {
+			t.Errorf("calls:got %q", calls)
+		}
+		if signed != "Bearer token" {
+			t.Errorf("Sign:got %q, want the header set by the hook", signed)
+		}
+	})
+
+	t.Run("BEFORE ERROR", func(t *testing.T) {
+		calls = nil
+		failed := errors.New("no token")
+		resp := NewRequest(NewClient().OnBeforeRequest(func(*Request) error { return failed })).Get(srv.URL)
+		if !errors.Is(resp.Error(), failed) {
+			t.Errorf("Error:got %v, want %v", resp.Error(), failed)
+		}
+	})
+
+	t.Run("AFTER ERROR", func(t *testing.T) {
+		var called bool
+		failed := errors.New("rejected")
+		cl := NewClient().
+			OnAfterResponse(func(*Request, *Response) error { return failed }).
+			OnAfterResponse(func(*Request, *Response) error { called = true; return nil })
+		resp := NewRequest(cl).Get(srv.URL)
+		if !errors.Is(resp.Error(), failed) || called {
+			t.Errorf("Error:got %v, next hook run %v", resp.Error(), called)
+		}
+	})
+}
@@ -0,0 +1,70 @@
+package www
+
+import (
+	"net/http"
+	"net/textproto"
+	"strings"
+)
+
+// hopByHopHeaders are the connection-specific headers HTTP/2 forbids
+// (RFC 7540, 8.1.2.2), besides the fields named by Connection (RFC 7230,
+// 6.1). TE is kept when it is "trailers".
+var hopByHopHeaders = []string{
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Connection",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// WithStripHopByHop removes the hop-by-hop headers from every request,
+// by default they are only removed from HTTPS requests that may go over
+// HTTP/2, where net/http rejects them.
+func (cl *StandardClient) WithStripHopByHop() *StandardClient {
+	cl.stripHopByHop = true
+	return cl
+}
+
+// mayUseHTTP2 reports whether the transport may negotiate HTTP/2 for req,
+// as net/http enables it for an *http.Transport. Other transports are
+// assumed to.
+func (cl *StandardClient) mayUseHTTP2(req *http.Request) bool {
+	if req.URL.Scheme != "https" {
+		return false
+	}
+	t, ok := cl.transport().(*http.Transport)
+	if !ok {
+		return true
+	}
+	if t.TLSNextProto != nil && t.TLSNextProto["h2"] == nil {
+		return false
+	}
+	return t.ForceAttemptHTTP2 || t.TLSClientConfig == nil && t.Dial == nil &&
+		t.DialContext == nil && t.DialTLS == nil && t.DialTLSContext == nil
+}
+
+// stripHopByHop removes the hop-by-hop headers a request copied from an
+// HTTP/1.1 context may carry, "Connection: close" is kept as Request.Close.
+func (r *Request) stripHopByHop() {
+	if !r.client.stripHopByHop && !r.client.mayUseHTTP2(r.Request) {
+		return
+	}
+	header := r.Request.Header
+
+	for _, value := range header["Connection"] {
+		for _, field := range strings.Split(value, ",") {
+			field = strings.TrimSpace(field)
+			if strings.EqualFold(field, "close") {
+				r.Request.Close = true
+			} else if field != "" {
+				header.Del(textproto.CanonicalMIMEHeaderKey(field))
+			}
+		}
+	}
+	for _, key := range hopByHopHeaders {
+		header.Del(key)
+	}
+	if te := header.Get("Te"); te != "" && !strings.EqualFold(strings.TrimSpace(te), "trailers") {
+		header.Del("Te")
+	}
+}
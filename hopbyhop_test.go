@@ -0,0 +1,51 @@
+package www
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestStripHopByHop(t *testing.T) {
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s|%s|%s|%s|%s", r.Proto, r.Header.Get("Connection"),
+			r.Header.Get("Keep-Alive"), r.Header.Get("X-Hop"), r.Header.Get("X-End"))
+	})
+	h2 := httptest.NewUnstartedServer(handler)
+	h2.EnableHTTP2 = true
+	h2.StartTLS()
+	defer h2.Close()
+	h1 := httptest.NewServer(handler)
+	defer h1.Close()
+
+	copied := http.Header{
+		"Connection": {"keep-alive, X-Hop"},
+		"Keep-Alive": {"timeout=5"},
+		"X-Hop":      {"1"},
+		"X-End":      {"2"},
+	}
+	send := func(cl *StandardClient, uri string) string {
+		r := NewRequest(cl)
+		for key, val := range copied {
+			r.WithHeader(key, val[0])
+		}
+		resp := r.Get(uri)
+		if resp.Error() != nil {
+			t.Fatalf("%v", resp.Error())
+		}
+		return resp.Text()
+	}
+
+	if got, want := send(NewClient(h2.Client()), h2.URL), "HTTP/2.0||||2"; got != want {
+		t.Errorf("h2:got %q, want %q", got, want)
+	}
+	if got, want := send(NewClient(), h1.URL), "HTTP/1.1|keep-alive, X-Hop|timeout=5|1|2"; got != want {
+		t.Errorf("h1:got %q, want %q", got, want)
+	}
+	// X-Hop goes with the Connection header naming it
+	if got, want := send(NewClient().WithStripHopByHop(), h1.URL), "HTTP/1.1||||2"; got != want {
+		t.Errorf("always:got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,38 @@
+package www
+
+import (
+	"crypto/rand"
+	"fmt"
+)
+
+// IdempotencyKey sends an Idempotency-Key header so the server can drop
+// the duplicates of a non-idempotent request, it also makes the request
+// retryable by the retry policy. The key identifies one logical
+// operation: it is the same for all the retries of the request, while
+// a Clone for another operation gets a new generated key. Without
+// a key, a random one is generated, a given key is kept by Clone.
+func (r *Request) IdempotencyKey(key ...string) *Request {
+	if len(key) > 0 && key[0] != "" {
+		r.idempotencyKey, r.generatedKey = key[0], false
+		return r
+	}
+	r.idempotencyKey, r.generatedKey = newIdempotencyKey(), true
+	return r
+}
+
+// newIdempotencyKey returns a random UUID (version 4).
+func newIdempotencyKey() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+func (r *Request) prepareIdempotencyKey() {
+	if r.idempotencyKey != "" {
+		r.Request.Header.Set("Idempotency-Key", r.idempotencyKey)
+	}
+}
@@ -0,0 +1,47 @@
+package www
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+)
+
+func TestIdempotencyKey(t *testing.T) {
+
+	var keys []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+		if len(keys)%2 == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	cl := NewClient().WithClock(&fakeClock{}).WithRetry(RetryPolicy{MaxRetries: 1})
+
+	payment := NewRequest(cl).JSON(map[string]int{"amount": 10}).IdempotencyKey()
+	refund := payment.Clone()
+	payment.Post(srv.URL)
+	refund.Post(srv.URL)
+
+	if len(keys) != 4 {
+		t.Fatalf("got %d requests, want 4", len(keys))
+	}
+	uuid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	if !uuid.MatchString(keys[0]) {
+		t.Errorf("not a UUID: %q", keys[0])
+	}
+	if keys[0] != keys[1] || keys[2] != keys[3] {
+		t.Errorf("the key changed across retries: %q", keys)
+	}
+	if keys[0] == keys[2] {
+		t.Errorf("two operations share the key %q", keys[0])
+	}
+
+	keys = nil
+	NewRequest(NewClient()).IdempotencyKey("order-42").Clone().Post(srv.URL)
+	if keys[0] != "order-42" {
+		t.Errorf("given key:got %q, want %q", keys[0], "order-42")
+	}
+}
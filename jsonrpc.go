@@ -0,0 +1,61 @@
+package www
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// JSONRPCError is the error object of a JSON-RPC 2.0 response.
+type JSONRPCError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *JSONRPCError) Error() string {
+	return fmt.Sprintf("json-rpc error %d: %s", e.Code, e.Message)
+}
+
+// JSONRPC sets a JSON-RPC 2.0 request as the body. A nil id makes it
+// a notification, the server sends no response object for it.
+func (r *Request) JSONRPC(method string, params interface{}, id interface{}) *Request {
+	return r.Json(struct {
+		JSONRPC string      `json:"jsonrpc"`
+		Method  string      `json:"method"`
+		Params  interface{} `json:"params,omitempty"`
+		ID      interface{} `json:"id,omitempty"`
+	}{"2.0", method, params, id})
+}
+
+// JSONRPC decodes the result of a JSON-RPC 2.0 response into result and
+// returns the error object as *JSONRPCError. An empty body, the answer
+// to a notification, is not an error.
+func (resp *Response) JSONRPC(result interface{}) error {
+	if resp.err != nil {
+		return resp.err
+	}
+	if resp.content == nil {
+		resp.content = resp.readAll(true)
+		if resp.err != nil {
+			return resp.err
+		}
+	}
+	if len(resp.content) == 0 {
+		return nil
+	}
+
+	var envelope struct {
+		Result json.RawMessage `json:"result"`
+		Error  *JSONRPCError   `json:"error"`
+	}
+	if err := json.Unmarshal(resp.content, &envelope); err != nil {
+		return err
+	}
+	if envelope.Error != nil {
+		return envelope.Error
+	}
+	if result == nil || envelope.Result == nil {
+		return nil
+	}
+	return json.Unmarshal(envelope.Result, result)
+}
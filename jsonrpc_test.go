@@ -0,0 +1,57 @@
+package www
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestJSONRPC(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			JSONRPC string
+			Method  string
+			Params  []int
+			ID      *int
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+
+		switch {
+		case req.ID == nil:
+			w.WriteHeader(http.StatusNoContent)
+		case req.Method == "sum" && req.JSONRPC == "2.0":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"jsonrpc": "2.0", "result": req.Params[0] + req.Params[1], "id": *req.ID,
+			})
+		default:
+			w.Write([]byte(`{"jsonrpc":"2.0","error":{"code":-32601,"message":"Method not found","data":"nope"},"id":1}`))
+		}
+	}))
+	defer srv.Close()
+
+	t.Run("RESULT", func(t *testing.T) {
+		var sum int
+		err := NewRequest(NewClient()).JSONRPC("sum", []int{2, 3}, 1).Post(srv.URL).JSONRPC(&sum)
+		if err != nil || sum != 5 {
+			t.Errorf("got %d (%v), want 5", sum, err)
+		}
+	})
+
+	t.Run("ERROR", func(t *testing.T) {
+		err := NewRequest(NewClient()).JSONRPC("unknown", nil, 1).Post(srv.URL).JSONRPC(nil)
+		var rpcErr *JSONRPCError
+		if !errors.As(err, &rpcErr) || rpcErr.Code != -32601 || string(rpcErr.Data) != `"nope"` {
+			t.Errorf("got %v", err)
+		}
+	})
+
+	t.Run("NOTIFICATION", func(t *testing.T) {
+		err := NewRequest(NewClient()).JSONRPC("sum", []int{2, 3}, nil).Post(srv.URL).JSONRPC(nil)
+		if err != nil {
+			t.Errorf("%v", err)
+		}
+	})
+}
@@ -0,0 +1,17 @@
+module github.com/GarryGaller/go-www/jsonschema
+
+go 1.20
+
+require (
+	github.com/GarryGaller/go-www v0.0.0
+	github.com/santhosh-tekuri/jsonschema/v5 v5.3.1
+)
+
+require (
+	github.com/andybalholm/brotli v1.0.6 // indirect
+	github.com/hashicorp/go-cleanhttp v0.5.2 // indirect
+	github.com/softlandia/cpd v0.0.0-20210117083209-2413526f2815 // indirect
+	golang.org/x/text v0.3.6 // indirect
+)
+
+replace github.com/GarryGaller/go-www => ../
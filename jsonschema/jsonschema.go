@@ -0,0 +1,69 @@
+// Package jsonschema checks the JSON bodies of www responses against a
+// JSON schema, a testing aid to catch API contract drift:
+//
+//	resp := www.NewRequest(cl).Get(usersURL)
+//	if err := jsonschema.Ensure(resp, userSchema); err != nil {
+//		t.Errorf("%v", err)
+//	}
+//
+// It is a module of its own, so www does not depend on the validator.
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/GarryGaller/go-www"
+	validator "github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+var ErrorSchemaMismatch = errors.New("the body does not match the JSON schema")
+
+// Ensure returns the error of resp, or an error wrapping
+// ErrorSchemaMismatch when the body does not conform to the schema, it
+// lists the failing paths of the body, e.g. "/items/0/id: expected
+// integer, but got string". The schema is compiled on every call. The
+// body is read once and kept for the following Content calls.
+func Ensure(resp *www.Response, schema string) error {
+	content, err := resp.Bytes()
+	if err != nil {
+		return err
+	}
+	sch, err := validator.CompileString("schema.json", schema)
+	if err != nil {
+		return err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(content))
+	dec.UseNumber()
+	var body interface{}
+	if err = dec.Decode(&body); err != nil {
+		return fmt.Errorf("%w: %v", ErrorSchemaMismatch, err)
+	}
+
+	err = sch.Validate(body)
+	var validationErr *validator.ValidationError
+	if errors.As(err, &validationErr) {
+		return fmt.Errorf("%w: %s", ErrorSchemaMismatch, strings.Join(failures(validationErr), "; "))
+	}
+	return err
+}
+
+// failures returns the leaf errors, the ones naming the keyword that
+// failed.
+func failures(ve *validator.ValidationError) (list []string) {
+	if len(ve.Causes) == 0 {
+		path := ve.InstanceLocation
+		if path == "" {
+			path = "/"
+		}
+		return []string{path + ": " + ve.Message}
+	}
+	for _, cause := range ve.Causes {
+		list = append(list, failures(cause)...)
+	}
+	return list
+}
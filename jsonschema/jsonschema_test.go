@@ -0,0 +1,65 @@
+package jsonschema_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/GarryGaller/go-www"
+	"github.com/GarryGaller/go-www/jsonschema"
+)
+
+func TestEnsure(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/drift":
+			w.Write([]byte(`{"id":"42","items":[{"name":"a"},{"name":1}]}`))
+		default:
+			w.Write([]byte(`{"id":42,"items":[{"name":"a"}]}`))
+		}
+	}))
+	defer srv.Close()
+
+	schema := `{
+		"type": "object",
+		"required": ["id", "items"],
+		"properties": {
+			"id": {"type": "integer"},
+			"items": {"type": "array", "items": {"properties": {"name": {"type": "string"}}}}
+		}
+	}`
+
+	resp := www.NewRequest(www.NewClient()).Get(srv.URL)
+	if err := jsonschema.Ensure(resp, schema); err != nil {
+		t.Errorf("conforming body:got %v", err)
+	}
+	if resp.Text() != `{"id":42,"items":[{"name":"a"}]}` {
+		t.Errorf("the body is not kept: %q", resp.Text())
+	}
+
+	err := jsonschema.Ensure(www.NewRequest(www.NewClient()).Get(srv.URL+"/drift"), schema)
+	if !errors.Is(err, jsonschema.ErrorSchemaMismatch) {
+		t.Fatalf("drift:got %v, want %v", err, jsonschema.ErrorSchemaMismatch)
+	}
+	for _, path := range []string{"/id: ", "/items/1/name: "} {
+		if !strings.Contains(err.Error(), path) {
+			t.Errorf("the error does not list %q: %v", path, err)
+		}
+	}
+	if strings.Contains(err.Error(), "/items/0") {
+		t.Errorf("the error lists a valid path: %v", err)
+	}
+
+	err = jsonschema.Ensure(www.NewRequest(www.NewClient()).Get(srv.URL), `{"type": 1}`)
+	if err == nil || errors.Is(err, jsonschema.ErrorSchemaMismatch) {
+		t.Errorf("invalid schema:got %v", err)
+	}
+
+	if err = jsonschema.Ensure(&www.Response{}, schema); !errors.Is(err, www.ErrorNoResponse) {
+		t.Errorf("no response:got %v, want %v", err, www.ErrorNoResponse)
+	}
+}
@@ -0,0 +1,69 @@
+package www
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// JSONStreamBuilder writes the elements of a JSON array body while the
+// request is sent, see Request.JSONStream.
+type JSONStreamBuilder struct {
+	pw *io.PipeWriter
+	n  int
+}
+
+// JSONStream sets a JSON array body streamed element by element, chunked,
+// for bulk uploads too large to buffer. The elements are written from
+// another goroutine while Post sends the request, every WriteObject blocks
+// until the server read the previous data:
+//
+//	stream := req.JSONStream()
+//	go func() {
+//		defer stream.Close()
+//		for _, rec := range records {
+//			if stream.WriteObject(rec) != nil {
+//				return
+//			}
+//		}
+//	}()
+//	resp := req.Post(uri)
+//
+// An element that fails to encode aborts the request with the error. Like
+// any stream, the body is not replayed on redirects or retries.
+func (r *Request) JSONStream() *JSONStreamBuilder {
+	pr, pw := io.Pipe()
+	r.mime = "application/json"
+	r.body = pr
+	r.getBody = nil
+	return &JSONStreamBuilder{pw: pw}
+}
+
+// WriteObject encodes v as the next element of the array. It fails when
+// the request is over or v cannot be encoded.
+func (s *JSONStreamBuilder) WriteObject(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		s.pw.CloseWithError(err)
+		return err
+	}
+	sep := byte(',')
+	if s.n == 0 {
+		sep = '['
+	}
+	s.n++
+	_, err = s.pw.Write(append([]byte{sep}, data...))
+	return err
+}
+
+// Close ends the array and the body.
+func (s *JSONStreamBuilder) Close() error {
+	end := "]"
+	if s.n == 0 {
+		end = "[]"
+	}
+	_, err := io.WriteString(s.pw, end)
+	if cerr := s.pw.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,76 @@
+package www
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestJSONStream(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var records []struct{ ID int }
+		if err := json.NewDecoder(r.Body).Decode(&records); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		last := -1
+		if len(records) > 0 {
+			last = records[len(records)-1].ID
+		}
+		fmt.Fprintf(w, "%s|%d|%v|%d", r.Header.Get("Content-Type"), len(records), r.TransferEncoding, last)
+	}))
+	defer srv.Close()
+
+	t.Run("many", func(t *testing.T) {
+		req := NewRequest(NewClient())
+		stream := req.JSONStream()
+		go func() {
+			defer stream.Close()
+			for i := 0; i < 100000; i++ {
+				if err := stream.WriteObject(struct{ ID int }{i}); err != nil {
+					return
+				}
+			}
+		}()
+
+		want := "application/json|100000|[chunked]|99999"
+		if got := req.Post(srv.URL).Text(); got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		req := NewRequest(NewClient())
+		stream := req.JSONStream()
+		go stream.Close()
+
+		want := "application/json|0|[chunked]|-1"
+		if got := req.Post(srv.URL).Text(); got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	})
+
+	t.Run("encoding error", func(t *testing.T) {
+		req := NewRequest(NewClient())
+		stream := req.JSONStream()
+		errs := make(chan error, 1)
+		go func() {
+			stream.WriteObject(1)
+			errs <- stream.WriteObject(func() {})
+		}()
+
+		resp := req.Post(srv.URL)
+		werr := <-errs
+		var unsupported *json.UnsupportedTypeError
+		if !errors.As(werr, &unsupported) {
+			t.Errorf("WriteObject:got %v", werr)
+		}
+		if !errors.As(resp.Error(), &unsupported) {
+			t.Errorf("request:got %v, want the encoding error", resp.Error())
+		}
+	})
+}
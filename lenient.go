@@ -0,0 +1,73 @@
+package www
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+)
+
+var jsonNumber = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+// StringNumber is a number field of a lenient API that is sent either as
+// a JSON number (123) or as a string ("123"). It is encoded as a number.
+// Use it instead of an int or float64 field, strict decoding stays the
+// default for every other field.
+type StringNumber json.Number
+
+func (n *StringNumber) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+	if data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		data = []byte(s)
+	}
+	// ParseFloat also takes NaN, Inf and hex floats, which are not JSON
+	if !jsonNumber.Match(data) {
+		return fmt.Errorf("%q is not a number", data)
+	}
+	*n = StringNumber(data)
+	return nil
+}
+
+func (n StringNumber) MarshalJSON() ([]byte, error) {
+	if n == "" {
+		return []byte("0"), nil
+	}
+	return []byte(n), nil
+}
+
+func (n StringNumber) Int64() (int64, error) {
+	return json.Number(n).Int64()
+}
+
+func (n StringNumber) Float64() (float64, error) {
+	return json.Number(n).Float64()
+}
+
+// NumberString is a string field of a lenient API that is sent either as
+// a JSON string or as a number or boolean, which are kept as written.
+type NumberString string
+
+func (s *NumberString) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+	if data[0] == '"' {
+		return json.Unmarshal(data, (*string)(s))
+	}
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	switch v.(type) {
+	case float64, bool:
+		*s = NumberString(data)
+		return nil
+	}
+	return fmt.Errorf("%s is not a string, number or boolean", data)
+}
@@ -0,0 +1,46 @@
+package www
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestLenientJSON(t *testing.T) {
+
+	var data []struct {
+		Count StringNumber
+		Code  NumberString
+	}
+	err := json.Unmarshal([]byte(`[
+		{"count": 123, "code": "A1"},
+		{"count": "456", "code": 789}
+	]`), &data)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if n, _ := data[0].Count.Int64(); n != 123 {
+		t.Errorf("number:got %d, want 123", n)
+	}
+	if n, _ := data[1].Count.Int64(); n != 456 {
+		t.Errorf("string to number:got %d, want 456", n)
+	}
+	if data[0].Code != "A1" || data[1].Code != "789" {
+		t.Errorf("number to string:got %q, %q", data[0].Code, data[1].Code)
+	}
+
+	if out, _ := json.Marshal(data[1].Count); string(out) != "456" {
+		t.Errorf("marshal:got %s, want 456", out)
+	}
+
+	for _, count := range []string{`"many"`, `"NaN"`, `"Inf"`, `"0x1p3"`, `"01"`, `" 1"`} {
+		var bad struct{ Count StringNumber }
+		if err := json.Unmarshal([]byte(`{"count": `+count+`}`), &bad); err == nil {
+			t.Errorf("%s:got %q, want an error", count, bad.Count)
+		}
+	}
+	var exp struct{ Count StringNumber }
+	if err := json.Unmarshal([]byte(`{"count": "-1.5e3"}`), &exp); err != nil || exp.Count != "-1.5e3" {
+		t.Errorf("exponent:got %q, %v", exp.Count, err)
+	}
+}
@@ -0,0 +1,185 @@
+package www
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+var ErrorLimitExceeded = errors.New("the request limit of the client is exceeded")
+
+// LimitOption configures WithRateLimit and WithMaxConcurrent.
+type LimitOption func(*limitGroup)
+
+// PerHost applies the limit to every host on its own instead of to all
+// the requests of the client together.
+func PerHost() LimitOption {
+	return func(g *limitGroup) {
+		g.perHost = true
+	}
+}
+
+// FailFast makes a request over the limit fail with ErrorLimitExceeded
+// instead of waiting.
+func FailFast() LimitOption {
+	return func(g *limitGroup) {
+		g.failFast = true
+	}
+}
+
+// WithRateLimit throttles the requests of the client to rps per second on
+// average with bursts of up to burst requests (a token bucket). Every
+// attempt, retries included, takes a token; a request waits for it on
+// the client clock until its context is done.
+func (cl *StandardClient) WithRateLimit(rps float64, burst int, opts ...LimitOption) *StandardClient {
+	if burst < 1 {
+		burst = 1
+	}
+	return cl.withLimit(func() limit {
+		return &tokenBucket{rps: rps, burst: float64(burst), tokens: float64(burst)}
+	}, opts)
+}
+
+// WithMaxConcurrent lets up to n requests of the client be in flight at
+// once, a request is done when its body is closed. The others wait until
+// their context is done.
+func (cl *StandardClient) WithMaxConcurrent(n int, opts ...LimitOption) *StandardClient {
+	if n < 1 {
+		n = 1
+	}
+	return cl.withLimit(func() limit {
+		return make(semaphore, n)
+	}, opts)
+}
+
+func (cl *StandardClient) withLimit(newLimit func() limit, opts []LimitOption) *StandardClient {
+	g := &limitGroup{newLimit: newLimit, limits: make(map[string]limit)}
+	for _, opt := range opts {
+		opt(g)
+	}
+	cl.limits = append(cl.limits, g)
+	return cl
+}
+
+type limit interface {
+	// acquire returns the function releasing what it took
+	acquire(ctx context.Context, cl *StandardClient, failFast bool) (func(), error)
+}
+
+type limitGroup struct {
+	mu       sync.Mutex
+	perHost  bool
+	failFast bool
+	newLimit func() limit
+	limits   map[string]limit
+}
+
+func (g *limitGroup) get(host string) limit {
+	if !g.perHost {
+		host = ""
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	l, ok := g.limits[host]
+	if !ok {
+		l = g.newLimit()
+		g.limits[host] = l
+	}
+	return l
+}
+
+type tokenBucket struct {
+	mu     sync.Mutex
+	rps    float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func (b *tokenBucket) acquire(ctx context.Context, cl *StandardClient, failFast bool) (func(), error) {
+	b.mu.Lock()
+	now := cl.now()
+	if !b.last.IsZero() {
+		b.tokens += now.Sub(b.last).Seconds() * b.rps
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+	}
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		b.mu.Unlock()
+		return func() {}, nil
+	}
+	if failFast {
+		b.mu.Unlock()
+		return nil, fmt.Errorf("%w: %v requests per second", ErrorLimitExceeded, b.rps)
+	}
+	// the token is taken now, the wait pays it back
+	wait := time.Duration((1 - b.tokens) / b.rps * float64(time.Second))
+	b.tokens--
+	b.mu.Unlock()
+
+	if err := cl.sleep(ctx, wait); err != nil {
+		b.mu.Lock()
+		b.tokens++
+		b.mu.Unlock()
+		return nil, err
+	}
+	return func() {}, nil
+}
+
+type semaphore chan struct{}
+
+func (s semaphore) acquire(ctx context.Context, _ *StandardClient, failFast bool) (func(), error) {
+	if failFast {
+		select {
+		case s <- struct{}{}:
+		default:
+			return nil, fmt.Errorf("%w: %d requests in flight", ErrorLimitExceeded, cap(s))
+		}
+	} else {
+		select {
+		case s <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	var once sync.Once
+	return func() { once.Do(func() { <-s }) }, nil
+}
+
+// acquireLimits waits for the limits of the client, the returned function
+// releases them.
+func (r *Request) acquireLimits() (func(), error) {
+	var releases []func()
+	release := func() {
+		for _, fn := range releases {
+			fn()
+		}
+	}
+	for _, g := range r.client.limits {
+		fn, err := g.get(r.Request.URL.Host).acquire(r.Request.Context(), r.client, g.failFast)
+		if err != nil {
+			release()
+			return nil, err
+		}
+		releases = append(releases, fn)
+	}
+	return release, nil
+}
+
+// releaseBody releases the limits once the body is closed.
+type releaseBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releaseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
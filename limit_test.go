@@ -0,0 +1,107 @@
+package www
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRateLimit(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	t.Run("WAIT", func(t *testing.T) {
+		clock := &fakeClock{now: time.Unix(0, 0)}
+		cl := NewClient().WithClock(clock).WithRateLimit(10, 2)
+		for i := 0; i < 4; i++ {
+			NewRequest(cl).Get(srv.URL).Content()
+		}
+		// the burst goes at once, then a request every 100ms
+		if fmt.Sprint(clock.slept) != "[100ms 100ms]" {
+			t.Errorf("slept:got %v, want [100ms 100ms]", clock.slept)
+		}
+	})
+
+	t.Run("FAIL FAST", func(t *testing.T) {
+		cl := NewClient().WithClock(&fakeClock{now: time.Unix(0, 0)}).WithRateLimit(1, 1, FailFast())
+		NewRequest(cl).Get(srv.URL).Content()
+		if resp := NewRequest(cl).Get(srv.URL); !errors.Is(resp.Error(), ErrorLimitExceeded) {
+			t.Errorf("Error:got %v, want %v", resp.Error(), ErrorLimitExceeded)
+		}
+	})
+
+	t.Run("PER HOST", func(t *testing.T) {
+		other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		defer other.Close()
+
+		clock := &fakeClock{now: time.Unix(0, 0)}
+		cl := NewClient().WithClock(clock).WithRateLimit(1, 1, PerHost())
+		NewRequest(cl).Get(srv.URL).Content()
+		NewRequest(cl).Get(other.URL).Content()
+		if len(clock.slept) != 0 {
+			t.Errorf("slept:got %v, want no wait", clock.slept)
+		}
+	})
+
+	t.Run("CONTEXT", func(t *testing.T) {
+		cl := NewClient().WithRateLimit(0.001, 1)
+		NewRequest(cl).Get(srv.URL).Content()
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		if resp := NewRequest(cl).WithContext(ctx).Get(srv.URL); !errors.Is(resp.Error(), context.DeadlineExceeded) {
+			t.Errorf("Error:got %v, want %v", resp.Error(), context.DeadlineExceeded)
+		}
+	})
+}
+
+func TestMaxConcurrent(t *testing.T) {
+
+	var mu sync.Mutex
+	var inFlight, most int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		if inFlight > most {
+			most = inFlight
+		}
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	cl := NewClient().WithMaxConcurrent(2)
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			NewRequest(cl).Get(srv.URL).Content()
+		}()
+	}
+	wg.Wait()
+	if most != 2 {
+		t.Errorf("in flight:got %d at most, want 2", most)
+	}
+
+	// the slot is held until the body is closed
+	cl = NewClient().WithMaxConcurrent(1, FailFast())
+	open := NewRequest(cl).Get(srv.URL)
+	if resp := NewRequest(cl).Get(srv.URL); !errors.Is(resp.Error(), ErrorLimitExceeded) ||
+		!strings.Contains(resp.Error().Error(), "1 requests in flight") {
+		t.Errorf("Error:got %v, want %v", resp.Error(), ErrorLimitExceeded)
+	}
+	open.Content()
+	if resp := NewRequest(cl).Get(srv.URL); resp.Error() != nil {
+		t.Errorf("after Close:got %v", resp.Error())
+	}
+}
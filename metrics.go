@@ -0,0 +1,82 @@
+package www
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// RequestMetric describes a finished exchange, it is passed to the
+// callback registered with WithMetrics.
+type RequestMetric struct {
+	Method     string
+	URL        string
+	StatusCode int // zero when the request failed
+	Duration   time.Duration
+	Err        error
+	// the operation ID set with Request.Operation, empty without it
+	Operation string
+	// logical names set with Request.WithTags, better metric labels than URLs
+	Tags map[string]string
+}
+
+// WithMetrics registers a callback invoked after every request.
+func (cl *StandardClient) WithMetrics(fn func(RequestMetric)) *StandardClient {
+	cl.metrics = fn
+	return cl
+}
+
+// WithTags attaches custom dimensions (endpoint name, tenant, ...) to the
+// request, they are passed to the metrics callback and are available to
+// logging through Tags.
+func (r *Request) WithTags(tags map[string]string) *Request {
+	if r.tags == nil {
+		r.tags = make(map[string]string, len(tags))
+	}
+	for key, val := range tags {
+		r.tags[key] = val
+	}
+	return r
+}
+
+// Operation names the logical operation of the request, such as an
+// OpenAPI operationId ("listUsers"), a low cardinality identifier passed
+// to the metrics callback along with the URL and logged by WithDebug.
+// Transports and hooks get it from the context of the http.Request with
+// OperationFromContext.
+func (r *Request) Operation(name string) *Request {
+	r.operation = name
+	return r
+}
+
+type operationKey struct{}
+
+// OperationFromContext returns the name set with Request.Operation, empty
+// without it.
+func OperationFromContext(ctx context.Context) string {
+	name, _ := ctx.Value(operationKey{}).(string)
+	return name
+}
+
+func (r *Request) Tags() map[string]string {
+	return r.tags
+}
+
+func (r *Request) emitMetric(start time.Time, resp *http.Response, err error) {
+	if r.client.metrics == nil {
+		return
+	}
+
+	metric := RequestMetric{
+		Method:    r.Request.Method,
+		URL:       r.Request.URL.String(),
+		Duration:  r.client.now().Sub(start),
+		Err:       err,
+		Operation: r.operation,
+		Tags:      r.tags,
+	}
+	if resp != nil {
+		metric.StatusCode = resp.StatusCode
+	}
+	r.client.metrics(metric)
+}
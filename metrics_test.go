@@ -0,0 +1,50 @@
+package www
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	var metrics []RequestMetric
+	var operation string
+	cl := NewClient().WithMetrics(func(m RequestMetric) {
+		metrics = append(metrics, m)
+	}).OnAfterResponse(func(r *Request, resp *Response) error {
+		operation = OperationFromContext(r.Request.Context())
+		return nil
+	})
+
+	NewRequest(cl).
+		WithTags(map[string]string{"endpoint": "users.list"}).
+		WithTags(map[string]string{"tenant": "acme"}).
+		Operation("listUsers").
+		Get(srv.URL + "/users?page=2")
+
+	if len(metrics) != 1 {
+		t.Fatalf("metrics:got %d, want 1", len(metrics))
+	}
+	m := metrics[0]
+	if m.StatusCode != http.StatusAccepted || m.Method != http.MethodGet {
+		t.Errorf("got %+v", m)
+	}
+	if m.Tags["endpoint"] != "users.list" || m.Tags["tenant"] != "acme" {
+		t.Errorf("Tags:got %v", m.Tags)
+	}
+	if m.Operation != "listUsers" {
+		t.Errorf("Operation:got %q, want %q", m.Operation, "listUsers")
+	}
+	if m.URL != srv.URL+"/users" {
+		t.Errorf("URL:got %q", m.URL)
+	}
+	if operation != "listUsers" {
+		t.Errorf("OperationFromContext:got %q, want %q", operation, "listUsers")
+	}
+}
@@ -0,0 +1,250 @@
+// Package mock provides an http.RoundTripper answering requests with
+// canned responses, to test code using www without a server:
+//
+//	m := mock.New()
+//	m.On("GET", "/users/42").ReplyJSON(200, User{ID: 42})
+//	cl := www.NewClient().WithTransport(m)
+//	...
+//	m.AssertExpectations(t)
+package mock
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+var ErrorUnexpectedRequest = errors.New("mock: unexpected request")
+
+// Transport answers the requests with the first expectation matching
+// them that is not used up, an unmatched request fails with
+// ErrorUnexpectedRequest.
+type Transport struct {
+	mu           sync.Mutex
+	expectations []*Expectation
+	unexpected   []string
+}
+
+func New() *Transport {
+	return &Transport{}
+}
+
+// On expects a request with method to url. A url starting with a slash
+// matches the path (and the query when it has one) of a request to any
+// host, otherwise the whole URL.
+func (m *Transport) On(method, url string) *Expectation {
+	e := &Expectation{mu: &m.mu, method: method, url: url, status: http.StatusOK, header: http.Header{}}
+	m.mu.Lock()
+	m.expectations = append(m.expectations, e)
+	m.mu.Unlock()
+	return e
+}
+
+func (m *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, e := range m.expectations {
+		if (e.times == 0 || e.calls < e.times) && e.matches(req, body) {
+			e.calls++
+			return e.respond(req)
+		}
+	}
+	call := req.Method + " " + req.URL.String()
+	m.unexpected = append(m.unexpected, call)
+	return nil, fmt.Errorf("%w: %s", ErrorUnexpectedRequest, call)
+}
+
+// AssertExpectations reports with t.Errorf the expectations not met: an
+// expectation with Times must be used exactly that many times, the others
+// at least once, and no unexpected request may have been made.
+func (m *Transport) AssertExpectations(t testing.TB) {
+	t.Helper()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, e := range m.expectations {
+		switch {
+		case e.times > 0 && e.calls != e.times:
+			t.Errorf("mock: %s %s:got %d calls, want %d", e.method, e.url, e.calls, e.times)
+		case e.times == 0 && e.calls == 0:
+			t.Errorf("mock: %s %s:not called", e.method, e.url)
+		}
+	}
+	for _, call := range m.unexpected {
+		t.Errorf("mock: unexpected request %s", call)
+	}
+}
+
+// Expectation matches requests and holds the response sent to them.
+type Expectation struct {
+	mu      *sync.Mutex // of the Transport
+	method  string
+	url     string
+	headers http.Header
+	body    func([]byte) bool
+	match   func(*http.Request) bool
+	times   int
+	calls   int
+
+	status  int
+	header  http.Header
+	payload []byte
+	err     error
+}
+
+// WithHeader matches the requests carrying the header with value.
+func (e *Expectation) WithHeader(key, value string) *Expectation {
+	if e.headers == nil {
+		e.headers = http.Header{}
+	}
+	e.headers.Add(key, value)
+	return e
+}
+
+// WithBody matches the requests with exactly this body.
+func (e *Expectation) WithBody(body string) *Expectation {
+	e.body = func(got []byte) bool {
+		return string(got) == body
+	}
+	return e
+}
+
+// WithJSONBody matches the requests with a JSON body equal to v once both
+// are decoded, the formatting and the order of the keys do not matter.
+func (e *Expectation) WithJSONBody(v interface{}) *Expectation {
+	want, err := json.Marshal(v)
+	e.body = func(got []byte) bool {
+		var a, b interface{}
+		return err == nil && json.Unmarshal(got, &a) == nil &&
+			json.Unmarshal(want, &b) == nil && reflect.DeepEqual(a, b)
+	}
+	return e
+}
+
+// Match adds a custom matcher, the body of req was read already.
+func (e *Expectation) Match(fn func(req *http.Request) bool) *Expectation {
+	e.match = fn
+	return e
+}
+
+// Times makes the expectation answer n requests only, 0 (the default)
+// answers all of them.
+func (e *Expectation) Times(n int) *Expectation {
+	e.times = n
+	return e
+}
+
+// Calls returns the number of requests the expectation answered.
+func (e *Expectation) Calls() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.calls
+}
+
+// Reply answers with status and body.
+func (e *Expectation) Reply(status int, body string) *Expectation {
+	e.status = status
+	e.payload = []byte(body)
+	return e
+}
+
+// ReplyJSON answers with status and v encoded as JSON.
+func (e *Expectation) ReplyJSON(status int, v interface{}) *Expectation {
+	e.status = status
+	e.payload, e.err = json.Marshal(v)
+	e.header.Set("Content-Type", "application/json")
+	return e
+}
+
+// ReplyFile answers with status and the content of the file at path,
+// application/json for a .json file.
+func (e *Expectation) ReplyFile(status int, path string) *Expectation {
+	e.status = status
+	e.payload, e.err = ioutil.ReadFile(path)
+	if strings.HasSuffix(path, ".json") {
+		e.header.Set("Content-Type", "application/json")
+	}
+	return e
+}
+
+// ReplyHeader adds a header to the response.
+func (e *Expectation) ReplyHeader(key, value string) *Expectation {
+	e.header.Add(key, value)
+	return e
+}
+
+// ReplyError fails the requests with err instead of answering them.
+func (e *Expectation) ReplyError(err error) *Expectation {
+	e.err = err
+	return e
+}
+
+func (e *Expectation) matches(req *http.Request, body []byte) bool {
+	if !strings.EqualFold(e.method, req.Method) {
+		return false
+	}
+	if strings.HasPrefix(e.url, "/") {
+		path := req.URL.Path
+		if strings.Contains(e.url, "?") {
+			path = req.URL.RequestURI()
+		}
+		if path != e.url {
+			return false
+		}
+	} else if req.URL.String() != e.url {
+		return false
+	}
+	for key, values := range e.headers {
+		for _, value := range values {
+			if !contains(req.Header.Values(key), value) {
+				return false
+			}
+		}
+	}
+	if e.body != nil && !e.body(body) {
+		return false
+	}
+	return e.match == nil || e.match(req)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *Expectation) respond(req *http.Request) (*http.Response, error) {
+	if e.err != nil {
+		return nil, e.err
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.status, http.StatusText(e.status)),
+		StatusCode:    e.status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        e.header.Clone(),
+		Body:          ioutil.NopCloser(bytes.NewReader(e.payload)),
+		ContentLength: int64(len(e.payload)),
+		Request:       req,
+	}, nil
+}
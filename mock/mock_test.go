@@ -0,0 +1,83 @@
+package mock_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/GarryGaller/go-www"
+	"github.com/GarryGaller/go-www/mock"
+)
+
+type user struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestTransport(t *testing.T) {
+	m := mock.New()
+	m.On("GET", "/users/42").ReplyJSON(http.StatusOK, user{42, "www"}).Times(1)
+	m.On("GET", "https://api.example.com/users/7").ReplyFile(http.StatusOK, "testdata/user.json")
+	m.On("POST", "/users").
+		WithHeader("Authorization", "Bearer token").
+		WithJSONBody(map[string]interface{}{"name": "new"}).
+		Reply(http.StatusCreated, "").
+		ReplyHeader("Location", "/users/43")
+	failed := errors.New("connection reset")
+	m.On("DELETE", "/users/42").ReplyError(failed)
+
+	cl := www.NewClient().WithTransport(m)
+
+	var u user
+	if err := www.NewRequest(cl).Get("http://api.example.com/users/42").Json(&u); err != nil || u.Name != "www" {
+		t.Errorf("JSON:got %+v, %v", u, err)
+	}
+	if err := www.NewRequest(cl).Get("https://api.example.com/users/7").Json(&u); err != nil || u.Name != "fixture" {
+		t.Errorf("fixture:got %+v, %v", u, err)
+	}
+	resp := www.NewRequest(cl).WithBearerToken("token").Json(map[string]string{"name": "new"}).
+		Post("http://api.example.com/users")
+	if resp.StatusCode != http.StatusCreated || resp.Header.Get("Location") != "/users/43" {
+		t.Errorf("POST:got %v, %v", resp.Error(), resp.Status)
+	}
+	if resp := www.NewRequest(cl).Delete("http://api.example.com/users/42"); !errors.Is(resp.Error(), failed) {
+		t.Errorf("ReplyError:got %v, want %v", resp.Error(), failed)
+	}
+	m.AssertExpectations(t)
+
+	// the single use of /users/42 is over
+	if resp := www.NewRequest(cl).Get("http://api.example.com/users/42"); !errors.Is(resp.Error(), mock.ErrorUnexpectedRequest) {
+		t.Errorf("used up:got %v, want %v", resp.Error(), mock.ErrorUnexpectedRequest)
+	}
+	rec := &recorder{TB: t}
+	m.AssertExpectations(rec)
+	if len(rec.errors) != 1 {
+		t.Errorf("AssertExpectations:got %q, want the unexpected request", rec.errors)
+	}
+}
+
+func TestAssertExpectations(t *testing.T) {
+	m := mock.New()
+	m.On("GET", "/once").Reply(http.StatusOK, "").Times(2)
+	m.On("GET", "/never").Reply(http.StatusOK, "")
+	www.NewRequest(www.NewClient().WithTransport(m)).Get("http://host/once")
+
+	rec := &recorder{TB: t}
+	m.AssertExpectations(rec)
+	want := `["mock: GET /once:got 1 calls, want 2" "mock: GET /never:not called"]`
+	if got := fmt.Sprintf("%q", rec.errors); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,233 @@
+package www
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+type formPart struct {
+	field       string
+	isFile      bool
+	fileName    string
+	contentType string
+	reader      io.Reader
+	start       int64 // -1 when the reader cannot seek back
+}
+
+func newFormPart(field string, reader io.Reader, contentType string) formPart {
+	part := formPart{field: field, contentType: contentType, reader: reader, start: -1}
+	if f, ok := reader.(*os.File); ok {
+		part.isFile = true
+		part.fileName = filepath.Base(f.Name())
+	}
+	if seeker, ok := reader.(io.Seeker); ok {
+		if start, err := seeker.Seek(0, io.SeekCurrent); err == nil {
+			part.start = start
+		}
+	}
+	return part
+}
+
+// FilePart is a file of a multipart form.
+type FilePart struct {
+	FileName    string
+	Reader      io.Reader
+	ContentType string // application/octet-stream when empty
+}
+
+// MultipartForm builds the multipart/form-data body of WithMultipart, the
+// parts are sent in the order they were added and a field may be repeated.
+type MultipartForm struct {
+	parts []formPart
+}
+
+func NewMultipartForm() *MultipartForm {
+	return &MultipartForm{}
+}
+
+// AddFile adds a file part, an empty filename is taken from an *os.File.
+func (form *MultipartForm) AddFile(field, filename string, r io.Reader, contentType string) *MultipartForm {
+	part := newFormPart(field, r, contentType)
+	part.isFile = true
+	if filename != "" {
+		part.fileName = filename
+	}
+	form.parts = append(form.parts, part)
+	return form
+}
+
+// AddFileN adds several files under the same field.
+func (form *MultipartForm) AddFileN(field string, files ...FilePart) *MultipartForm {
+	for _, file := range files {
+		form.AddFile(field, file.FileName, file.Reader, file.ContentType)
+	}
+	return form
+}
+
+// AddField adds a plain text field.
+func (form *MultipartForm) AddField(field, value string) *MultipartForm {
+	form.parts = append(form.parts, newFormPart(field, strings.NewReader(value), ""))
+	return form
+}
+
+// WithMultipart sends the form as a multipart/form-data body. Like with
+// AttachFile, the parts are streamed, replayed by seeking back and closed
+// once the request is done.
+func (r *Request) WithMultipart(form *MultipartForm) *Request {
+	return r.withMultipart(form.parts)
+}
+
+// multipartBody streams the parts through a pipe instead of buffering
+// them, the multipart writer runs in a goroutine and an error reading
+// a part fails the request. The body is replayed by seeking the parts
+// back, the files are closed with the body once Do is done.
+type multipartBody struct {
+	parts    []formPart
+	boundary string
+
+	mu     sync.Mutex
+	body   *io.PipeReader // read by Read, the first body
+	pr     *io.PipeReader // of the last writer
+	done   chan struct{}  // closed once the last writer is done
+	opened bool
+}
+
+// errBodyReopened stops the writer of a body sent before it is replayed.
+var errBodyReopened = errors.New("the body is sent again")
+
+func newMultipartBody(parts []formPart) *multipartBody {
+	return &multipartBody{
+		parts:    parts,
+		boundary: multipart.NewWriter(nil).Boundary(),
+	}
+}
+
+func (m *multipartBody) contentType() string {
+	return "multipart/form-data; boundary=" + m.boundary
+}
+
+// open starts writing the parts from their start, it is the GetBody of
+// the request. The parts are shared, so the previous writer is stopped
+// and waited for before they are seeked back.
+func (m *multipartBody) open() (io.ReadCloser, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.openLocked()
+}
+
+func (m *multipartBody) openLocked() (*io.PipeReader, error) {
+	if m.pr != nil {
+		m.pr.CloseWithError(errBodyReopened)
+		<-m.done
+	}
+	if m.opened {
+		for _, part := range m.parts {
+			if part.start < 0 {
+				return nil, ErrorBodyNotReplay
+			}
+			if _, err := part.reader.(io.Seeker).Seek(part.start, io.SeekStart); err != nil {
+				return nil, err
+			}
+		}
+	}
+	m.opened = true
+
+	pr, pw := io.Pipe()
+	done := make(chan struct{})
+	m.pr, m.done = pr, done
+	go func() {
+		defer close(done)
+		pw.CloseWithError(m.write(pw))
+	}()
+	return pr, nil
+}
+
+func (m *multipartBody) write(w io.Writer) error {
+	writer := multipart.NewWriter(w)
+	if err := writer.SetBoundary(m.boundary); err != nil {
+		return err
+	}
+
+	for _, part := range m.parts {
+		var dst io.Writer
+		var err error
+		if part.isFile && part.contentType != "" {
+			dst, err = CreateFormFile(writer, part.field, part.fileName, part.contentType)
+		} else if part.isFile {
+			dst, err = CreateFormFile(writer, part.field, part.fileName)
+		} else {
+			dst, err = writer.CreateFormField(part.field)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", part.field, err)
+		}
+		if _, err = io.Copy(dst, part.reader); err != nil {
+			return fmt.Errorf("%s: %w", part.field, err)
+		}
+	}
+	return writer.Close()
+}
+
+func (m *multipartBody) Read(p []byte) (int, error) {
+	m.mu.Lock()
+	if m.body == nil {
+		body, err := m.openLocked()
+		if err != nil {
+			m.mu.Unlock()
+			return 0, err
+		}
+		m.body = body
+	}
+	body := m.body
+	m.mu.Unlock()
+	return body.Read(p)
+}
+
+// Close stops the writer and closes the parts once it is done.
+func (m *multipartBody) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.pr != nil {
+		m.pr.Close()
+		<-m.done
+	}
+	for _, part := range m.parts {
+		closeReader(part.reader)
+	}
+	return nil
+}
+
+var ErrorInvalidBoundary = errors.New("invalid multipart boundary")
+
+// WithMultipartBody sends a multipart body built elsewhere, e.g. a captured
+// upload, with the Content-Type multipart/form-data and the boundary. It is
+// not WithMultipart, that one builds the body from a MultipartForm.
+// Buffered bodies (*bytes.Buffer, *bytes.Reader, ...) are replayed.
+func (r *Request) WithMultipartBody(body io.Reader, boundary string) *Request {
+	if err := multipart.NewWriter(nil).SetBoundary(boundary); err != nil {
+		r.err = fmt.Errorf("%w: %q: %v", ErrorInvalidBoundary, boundary, err)
+		return r
+	}
+	r.mime = mime.FormatMediaType("multipart/form-data", map[string]string{"boundary": boundary})
+	r.body = body
+	r.getBody = nil
+	r.length = 0
+	return r
+}
+
+// withMultipart makes the parts the streamed body of the request.
+func (r *Request) withMultipart(parts []formPart) *Request {
+	body := newMultipartBody(parts)
+	r.mime = body.contentType()
+	r.body = body
+	r.getBody = body.open
+	r.length = -1
+	return r
+}
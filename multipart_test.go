@@ -0,0 +1,231 @@
+package www
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, errors.New("disk failure")
+}
+
+func TestAttachFileStreaming(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/upload" {
+			http.Redirect(w, r, "/final", http.StatusTemporaryRedirect)
+			return
+		}
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var parts []string
+		for field, headers := range r.MultipartForm.File {
+			f, _ := headers[0].Open()
+			data, _ := io.ReadAll(f)
+			parts = append(parts, fmt.Sprintf("%s=%s:%s:%s", field, headers[0].Filename, headers[0].Header.Get("Content-Type"), data))
+		}
+		for field, values := range r.MultipartForm.Value {
+			parts = append(parts, field+"="+values[0])
+		}
+		fmt.Fprintf(w, "%v|%s", r.TransferEncoding, strings.Join(parts, ","))
+	}))
+	defer srv.Close()
+
+	create := func(name, content string) *os.File {
+		path := filepath.Join(t.TempDir(), name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return MustOpen(path)
+	}
+
+	t.Run("REDIRECT", func(t *testing.T) {
+		f := create("notes.txt", "hello world")
+		got := NewRequest(NewClient()).AttachFile(f, "text/plain").Post(srv.URL + "/upload").Text()
+		if want := "[chunked]|file=notes.txt:text/plain:hello world"; got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+		if _, err := f.Stat(); err == nil {
+			t.Errorf("the file is not closed")
+		}
+	})
+
+	t.Run("REPLAY", func(t *testing.T) {
+		content := strings.Repeat("x", 1<<20)
+		body := newMultipartBody([]formPart{
+			newFormPart("file", create("big.txt", content), ""),
+			newFormPart("note", strings.NewReader(content), ""),
+		})
+		defer body.Close()
+		first, _ := body.open()
+		go io.Copy(io.Discard, first) // the attempt sent before is still writing
+		second, err := body.open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader := multipart.NewReader(second, body.boundary)
+		for _, field := range []string{"file", "note"} {
+			part, err := reader.NextPart()
+			if err != nil {
+				t.Fatalf("%s: %v", field, err)
+			}
+			if data, _ := io.ReadAll(part); part.FormName() != field || string(data) != content {
+				t.Errorf("%s:got %d bytes, want %d", part.FormName(), len(data), len(content))
+			}
+		}
+	})
+
+	t.Run("FILES AND FIELDS", func(t *testing.T) {
+		got := NewRequest(NewClient()).AttachFiles(map[string][]interface{}{
+			"doc":  {create("doc.txt", "data")},
+			"note": {strings.NewReader("value")},
+		}).Post(srv.URL).Text()
+		if want := "[chunked]|doc=doc.txt:application/octet-stream:data,note=value"; got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	})
+
+	t.Run("READ ERROR", func(t *testing.T) {
+		resp := NewRequest(NewClient()).AttachFiles(map[string][]interface{}{
+			"broken": {failingReader{}},
+		}).Post(srv.URL)
+		if resp.Error() == nil || !strings.Contains(resp.Error().Error(), "disk failure") {
+			t.Errorf("Error:got %v, want the read error", resp.Error())
+		}
+	})
+}
+
+func TestWithMultipart(t *testing.T) {
+
+	var flaky int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/flaky" && atomic.AddInt32(&flaky, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable) // before the body is read
+			return
+		}
+		reader, err := r.MultipartReader()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var parts []string
+		for {
+			part, err := reader.NextPart()
+			if err != nil {
+				break
+			}
+			data, _ := io.ReadAll(part)
+			parts = append(parts, fmt.Sprintf("%s:%s:%s:%s", part.FormName(), part.FileName(), part.Header.Get("Content-Type"), data))
+		}
+		w.Write([]byte(strings.Join(parts, ",")))
+	}))
+	defer srv.Close()
+
+	form := NewMultipartForm().
+		AddField("title", "report").
+		AddFileN("attachment",
+			FilePart{FileName: "a.csv", Reader: strings.NewReader("1,2"), ContentType: "text/csv"},
+			FilePart{FileName: "b.bin", Reader: strings.NewReader("\x00")},
+		).
+		AddFile("cover", "cover.txt", strings.NewReader("hi"), "text/plain")
+
+	got := NewRequest(NewClient()).WithMultipart(form).Post(srv.URL).Text()
+	want := "title:::report,attachment:a.csv:text/csv:1,2,attachment:b.bin:application/octet-stream:\x00,cover:cover.txt:text/plain:hi"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// replayed while the first attempt may still be writing
+	content := strings.Repeat("x", 1<<20)
+	cl := NewClient().WithClock(&fakeClock{}).WithRetry(RetryPolicy{MaxRetries: 1})
+	got = NewRequest(cl).WithMultipart(NewMultipartForm().AddField("big", content)).Put(srv.URL + "/flaky").Text()
+	if want := "big:::" + content; got != want {
+		t.Errorf("replayed:got %d bytes, want %d", len(got), len(want))
+	}
+}
+
+func TestWithMultipartBody(t *testing.T) {
+
+	var flaky int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/flaky" && atomic.AddInt32(&flaky, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable) // before the body is read
+			return
+		}
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		fmt.Fprintf(w, "%s|%s", r.Header.Get("Content-Type"), r.FormValue("name"))
+	}))
+	defer srv.Close()
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	writer.SetBoundary("precomputed")
+	writer.WriteField("name", "value")
+	writer.Close()
+
+	got := NewRequest(NewClient()).
+		WithMultipartBody(bytes.NewReader(body.Bytes()), "precomputed").
+		Post(srv.URL).Text()
+	if want := "multipart/form-data; boundary=precomputed|value"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	cl := NewClient().WithClock(&fakeClock{}).WithRetry(RetryPolicy{MaxRetries: 1})
+	got = NewRequest(cl).
+		WithMultipartBody(bytes.NewReader(body.Bytes()), "precomputed").
+		Put(srv.URL + "/flaky").Text()
+	if want := "multipart/form-data; boundary=precomputed|value"; got != want {
+		t.Errorf("replayed:got %q, want %q", got, want)
+	}
+
+	r := NewRequest(NewClient()).WithMultipartBody(&body, "bad\nboundary")
+	if !errors.Is(r.Error(), ErrorInvalidBoundary) {
+		t.Errorf("Error:got %v, want %v", r.Error(), ErrorInvalidBoundary)
+	}
+}
+
+func TestAttachFilesErrors(t *testing.T) {
+
+	var hits int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+	}))
+	defer srv.Close()
+
+	resp := NewRequest(NewClient()).AttachFiles(map[string][]interface{}{
+		"empty":  {},
+		"number": {42},
+		"type":   {strings.NewReader("data"), 1},
+		"ok":     {strings.NewReader("data")},
+	}).Post(srv.URL)
+
+	err := resp.Error()
+	if !errors.Is(err, ErrorEmptyListValues) {
+		t.Errorf("Error:got %v, want %v", err, ErrorEmptyListValues)
+	}
+	for _, field := range []string{"empty:", "number: value int", "type: content type int"} {
+		if err == nil || !strings.Contains(err.Error(), field) {
+			t.Errorf("the error does not name %q: %v", field, err)
+		}
+	}
+	if hits != 0 {
+		t.Errorf("the malformed request was sent")
+	}
+}
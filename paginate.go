@@ -0,0 +1,44 @@
+package www
+
+import (
+	"net/url"
+)
+
+// PaginateCursor GETs startURL and the following pages of a cursor based
+// API: extract returns the next cursor found in a page, e.g. the
+// next_cursor member of the JSON body, and the next page is startURL with
+// the cursor as the cursorParam query parameter. It stops without error
+// on an empty cursor, on the first failed request or error of extract
+// otherwise. The body of every page is closed after extract.
+func (cl *StandardClient) PaginateCursor(startURL string, cursorParam string,
+	extract func(*Response) (nextCursor string, err error)) error {
+
+	// the query of the uri passed to Get is replaced by the one of the
+	// request, so the parameters of startURL go through Query
+	start, err := url.Parse(startURL)
+	if err != nil {
+		return err
+	}
+	query := start.Query()
+	start.RawQuery = ""
+	uri := start.String()
+
+	for {
+		resp := NewRequest(cl).Query(query).Get(uri)
+		if resp.Error() != nil {
+			return resp.Error()
+		}
+		cursor, err := extract(resp)
+		resp.Body.Close()
+		if err != nil || cursor == "" {
+			return err
+		}
+
+		next := make(url.Values, len(query))
+		for key, val := range query {
+			next[key] = val
+		}
+		next.Set(cursorParam, cursor)
+		query = next
+	}
+}
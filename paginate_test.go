@@ -0,0 +1,73 @@
+package www
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPaginateCursor(t *testing.T) {
+
+	pages := map[string]string{
+		"":   `{"items":["a","b"],"next_cursor":"c1"}`,
+		"c1": `{"items":["c"],"next_cursor":"c2"}`,
+		"c2": `{"items":["d"],"next_cursor":""}`,
+	}
+	var queries []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.RawQuery)
+		page, ok := pages[r.URL.Query().Get("cursor")]
+		if !ok {
+			http.Error(w, "unknown cursor", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(page))
+	}))
+	defer srv.Close()
+
+	var items []string
+	err := NewClient().PaginateCursor(srv.URL+"/items?limit=2", "cursor", func(resp *Response) (string, error) {
+		var page struct {
+			Items      []string `json:"items"`
+			NextCursor string   `json:"next_cursor"`
+		}
+		if err := resp.Json(&page); err != nil {
+			return "", err
+		}
+		items = append(items, page.Items...)
+		return page.NextCursor, nil
+	})
+
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if fmt.Sprint(items) != "[a b c d]" {
+		t.Errorf("items:got %v, want [a b c d]", items)
+	}
+	if want := "[limit=2 cursor=c1&limit=2 cursor=c2&limit=2]"; fmt.Sprint(queries) != want {
+		t.Errorf("queries:got %v, want %v", queries, want)
+	}
+
+	errStop := errors.New("stop")
+	err = NewClient().PaginateCursor(srv.URL, "cursor", func(resp *Response) (string, error) {
+		return "", errStop
+	})
+	if err != errStop {
+		t.Errorf("extract error:got %v, want %v", err, errStop)
+	}
+
+	// the second page answers 400, Json reports it
+	var pages2 int
+	err = NewClient().PaginateCursor(srv.URL, "cursor", func(resp *Response) (string, error) {
+		pages2++
+		var page map[string]interface{}
+		return "unknown", resp.Json(&page)
+	})
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusBadRequest || pages2 != 2 {
+		t.Errorf("failed page:got %v after %d pages, want an *HTTPError after 2", err, pages2)
+	}
+}
@@ -0,0 +1,30 @@
+package www
+
+import (
+	"context"
+	"time"
+)
+
+// PollUntil GETs uri every interval until done returns true or an error,
+// or ctx is done, e.g. to wait for an asynchronous job. done gets every
+// response, failed ones included (see Response.Error), the bodies of the
+// intermediate responses are closed. The final response is returned with
+// the error of done or of ctx.
+func (cl *StandardClient) PollUntil(ctx context.Context, uri string, interval time.Duration,
+	done func(*Response) (bool, error)) (*Response, error) {
+
+	for {
+		resp := NewRequest(cl).WithContext(ctx).Get(uri)
+		ok, err := done(resp)
+		if ok || err != nil {
+			return resp, err
+		}
+		if resp.Response != nil {
+			resp.Body.Close()
+		}
+
+		if err := cl.sleep(ctx, interval); err != nil {
+			return resp, err
+		}
+	}
+}
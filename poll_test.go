@@ -0,0 +1,54 @@
+package www
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPollUntil(t *testing.T) {
+
+	var polls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		polls++
+		if polls < 3 {
+			w.Write([]byte("pending"))
+			return
+		}
+		fmt.Fprintf(w, "done")
+	}))
+	defer srv.Close()
+
+	clock := &fakeClock{}
+	cl := NewClient().WithClock(clock)
+
+	t.Run("DONE", func(t *testing.T) {
+		resp, err := cl.PollUntil(context.Background(), srv.URL, time.Second, func(resp *Response) (bool, error) {
+			return resp.Text() == "done", resp.Error()
+		})
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		if resp.Text() != "done" || polls != 3 {
+			t.Errorf("got %q after %d polls", resp.Text(), polls)
+		}
+		if len(clock.slept) != 2 || clock.slept[0] != time.Second {
+			t.Errorf("slept:got %v, want 2 intervals of 1s", clock.slept)
+		}
+	})
+
+	t.Run("CONTEXT", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		_, err := cl.PollUntil(ctx, srv.URL, time.Second, func(resp *Response) (bool, error) {
+			cancel()
+			return false, nil
+		})
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("got %v, want %v", err, context.Canceled)
+		}
+	})
+}
@@ -0,0 +1,117 @@
+package www
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"sync/atomic"
+)
+
+// PoolStats describes the use of the connection pool of a client.
+type PoolStats struct {
+	Dialed int64 // connections opened
+	// connections closed by the pool: evicted idle connections (idle
+	// timeout, MaxIdleConns exceeded) and broken ones
+	Closed   int64
+	Open     int64 // Dialed - Closed
+	Requests int64 // requests that got a connection
+	Reused   int64 // requests sent on an idle connection of the pool
+}
+
+// ReuseRate is the share of the requests sent on a reused connection.
+func (s PoolStats) ReuseRate() float64 {
+	if s.Requests == 0 {
+		return 0
+	}
+	return float64(s.Reused) / float64(s.Requests)
+}
+
+type poolCounters struct {
+	dialed, closed, requests, reused int64
+}
+
+// WithPoolStats instruments the dialer of the transport to count the
+// connections opened and closed, see PoolStats. The counters are atomic,
+// their cost is negligible.
+func (cl *StandardClient) WithPoolStats() *StandardClient {
+	t, err := cl.httpTransport()
+	if err != nil {
+		cl.err = err
+		return cl
+	}
+	if cl.pool != nil {
+		return cl
+	}
+
+	counters := &poolCounters{}
+	dial := t.DialContext
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+	t.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		atomic.AddInt64(&counters.dialed, 1)
+		return &countingConn{Conn: conn, closed: &counters.closed}, nil
+	}
+	cl.pool = counters
+	return cl
+}
+
+// WithMaxIdleConns sets the size of the pool of idle connections and
+// optionally the size per host (2 by default in net/http).
+func (cl *StandardClient) WithMaxIdleConns(n int, perHost ...int) *StandardClient {
+	t, err := cl.httpTransport()
+	if err != nil {
+		cl.err = err
+		return cl
+	}
+	t.MaxIdleConns = n
+	if len(perHost) > 0 {
+		t.MaxIdleConnsPerHost = perHost[0]
+	}
+	return cl
+}
+
+// PoolStats returns the counters of WithPoolStats, zero without it.
+func (cl *StandardClient) PoolStats() (stats PoolStats) {
+	if cl.pool == nil {
+		return stats
+	}
+	stats.Dialed = atomic.LoadInt64(&cl.pool.dialed)
+	stats.Closed = atomic.LoadInt64(&cl.pool.closed)
+	stats.Open = stats.Dialed - stats.Closed
+	stats.Requests = atomic.LoadInt64(&cl.pool.requests)
+	stats.Reused = atomic.LoadInt64(&cl.pool.reused)
+	return stats
+}
+
+// traceConn counts the requests on reused connections.
+func (cl *StandardClient) traceConn(req *http.Request) *http.Request {
+	if cl.pool == nil {
+		return req
+	}
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			atomic.AddInt64(&cl.pool.requests, 1)
+			if info.Reused {
+				atomic.AddInt64(&cl.pool.reused, 1)
+			}
+		},
+	}))
+}
+
+type countingConn struct {
+	net.Conn
+	once   sync.Once
+	closed *int64
+}
+
+func (c *countingConn) Close() error {
+	c.once.Do(func() { atomic.AddInt64(c.closed, 1) })
+	return c.Conn.Close()
+}
@@ -0,0 +1,42 @@
+package www
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPoolStats(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	cl := NewClient().WithMaxIdleConns(10, 1).WithPoolStats()
+	for i := 0; i < 3; i++ {
+		NewRequest(cl).Get(srv.URL).Content()
+	}
+
+	stats := cl.PoolStats()
+	if stats.Dialed != 1 || stats.Requests != 3 || stats.Reused != 2 || stats.Open != 1 {
+		t.Errorf("got %+v", stats)
+	}
+	if rate := stats.ReuseRate(); rate < 0.66 || rate > 0.67 {
+		t.Errorf("ReuseRate:got %v, want 2/3", rate)
+	}
+
+	cl.CloseIdleConnections()
+	deadline := time.Now().Add(time.Second)
+	for cl.PoolStats().Closed != 1 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if stats := cl.PoolStats(); stats.Closed != 1 || stats.Open != 0 {
+		t.Errorf("evicted:got %+v", stats)
+	}
+
+	if stats := NewClient().PoolStats(); stats != (PoolStats{}) {
+		t.Errorf("disabled:got %+v", stats)
+	}
+}
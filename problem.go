@@ -0,0 +1,104 @@
+package www
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+)
+
+var ErrorNotProblem = errors.New("the body is not application/problem+json")
+
+// Problem is an RFC 7807 problem details document.
+type Problem struct {
+	Type     string `json:"type,omitempty"`
+	Title    string `json:"title,omitempty"`
+	Status   int    `json:"status,omitempty"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+}
+
+func (p *Problem) Error() string {
+	msg := fmt.Sprintf("problem %d", p.Status)
+	if p.Title != "" {
+		msg += ": " + p.Title
+	}
+	if p.Detail != "" {
+		msg += ": " + p.Detail
+	}
+	return msg
+}
+
+// HTTPError is the error of a 4xx or 5xx response, set by EnsureStatus and
+// returned by Json. It matches ErrorUnexpectedStatus with errors.Is and,
+// for a problem+json body, the *Problem with errors.As.
+type HTTPError struct {
+	StatusCode int
+	Status     string
+	Problem    *Problem
+}
+
+func (e *HTTPError) Error() string {
+	msg := fmt.Sprintf("%s: %s", ErrorUnexpectedStatus, e.Status)
+	if e.Problem != nil && e.Problem.Detail != "" {
+		msg += ": " + e.Problem.Detail
+	}
+	return msg
+}
+
+func (e *HTTPError) Unwrap() []error {
+	if e.Problem != nil {
+		return []error{ErrorUnexpectedStatus, e.Problem}
+	}
+	return []error{ErrorUnexpectedStatus}
+}
+
+// Problem decodes an application/problem+json body, the status member
+// defaults to the status code of the response. The body is read once and
+// kept for the following Content calls.
+func (resp *Response) Problem() (*Problem, error) {
+	if resp.err != nil {
+		var httpErr *HTTPError
+		if errors.As(resp.err, &httpErr) && httpErr.Problem != nil {
+			return httpErr.Problem, nil
+		}
+		return nil, resp.err
+	}
+	if resp.Response == nil {
+		return nil, ErrorNoResponse
+	}
+	return resp.problem()
+}
+
+func (resp *Response) problem() (*Problem, error) {
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "application/problem+json" {
+		return nil, fmt.Errorf("%w: %q", ErrorNotProblem, mediaType)
+	}
+	if resp.content == nil {
+		resp.content = resp.readAll(true)
+		if resp.err != nil {
+			return nil, resp.err
+		}
+	}
+
+	problem := &Problem{}
+	if err := json.Unmarshal(resp.content, problem); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrorNotProblem, err)
+	}
+	if problem.Status == 0 {
+		problem.Status = resp.StatusCode
+	}
+	return problem, nil
+}
+
+// statusError builds the HTTPError of the response, a problem+json body
+// is read and decoded into it.
+func (resp *Response) statusError() error {
+	httpErr := &HTTPError{StatusCode: resp.StatusCode, Status: resp.Status}
+	if resp.StatusCode >= http.StatusBadRequest {
+		httpErr.Problem, _ = resp.problem()
+	}
+	return httpErr
+}
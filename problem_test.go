@@ -0,0 +1,79 @@
+package www
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestProblem(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/plain" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte("forbidden"))
+			return
+		}
+		w.Header().Set("Content-Type", "application/problem+json")
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"type":"https://example.com/probs/out-of-credit",` +
+			`"title":"You do not have enough credit.","detail":"Your current balance is 30, but that costs 50.",` +
+			`"instance":"/account/12345/msgs/abc"}`))
+	}))
+	defer srv.Close()
+
+	want := Problem{
+		Type:     "https://example.com/probs/out-of-credit",
+		Title:    "You do not have enough credit.",
+		Status:   http.StatusForbidden,
+		Detail:   "Your current balance is 30, but that costs 50.",
+		Instance: "/account/12345/msgs/abc",
+	}
+
+	t.Run("Problem", func(t *testing.T) {
+		problem, err := NewRequest(NewClient()).Get(srv.URL).Problem()
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		if *problem != want {
+			t.Errorf("got %+v, want %+v", *problem, want)
+		}
+	})
+
+	t.Run("errors.As", func(t *testing.T) {
+		var data map[string]interface{}
+		err := NewRequest(NewClient()).Get(srv.URL).Json(&data)
+
+		var problem *Problem
+		if !errors.As(err, &problem) || *problem != want {
+			t.Fatalf("Problem:got %v, want %+v", err, want)
+		}
+		var httpErr *HTTPError
+		if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusForbidden {
+			t.Errorf("HTTPError:got %v", err)
+		}
+		if !errors.Is(err, ErrorUnexpectedStatus) {
+			t.Errorf("status:got %v, want %v", err, ErrorUnexpectedStatus)
+		}
+
+		resp := NewRequest(NewClient()).Get(srv.URL).EnsureStatus(http.StatusOK)
+		if !errors.As(resp.Error(), &problem) || problem.Title != want.Title {
+			t.Errorf("EnsureStatus:got %v", resp.Error())
+		}
+	})
+
+	t.Run("not a problem", func(t *testing.T) {
+		resp := NewRequest(NewClient()).Get(srv.URL + "/plain")
+		if _, err := resp.Problem(); !errors.Is(err, ErrorNotProblem) {
+			t.Errorf("got %v, want %v", err, ErrorNotProblem)
+		}
+		var problem *Problem
+		if err := resp.EnsureStatus(http.StatusOK).Error(); errors.As(err, &problem) {
+			t.Errorf("got problem %+v for a plain body", problem)
+		}
+		if resp.Text() != "forbidden" {
+			t.Errorf("the body is read: %q", resp.Text())
+		}
+	})
+}
@@ -0,0 +1,125 @@
+package www
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+var (
+	ErrorNoLocation    = errors.New("the response has no Location header")
+	ErrorNotRedirect   = errors.New("the response is not a redirect")
+	ErrorBodyNotReplay = errors.New("the request body cannot be sent again")
+)
+
+// MaxRedirects overrides WithMaxRedirects of the client for this request.
+func (r *Request) MaxRedirects(n int) *Request {
+	r.maxRedirects = n
+	if n <= 0 {
+		r.maxRedirects = -1
+	}
+	return r
+}
+
+// NoRedirects returns the redirect response of this request as it is.
+func (r *Request) NoRedirects() *Request {
+	r.maxRedirects = -1
+	return r
+}
+
+// RedirectPolicy decides on the redirects of this request in place of the
+// CheckRedirect and the redirect limit of the client, with the same
+// contract as http.Client.CheckRedirect: http.ErrUseLastResponse returns
+// the redirect response, another error fails the request.
+func (r *Request) RedirectPolicy(fn func(req *http.Request, via []*http.Request) error) *Request {
+	r.redirectPolicy = fn
+	return r
+}
+
+// Redirects returns the URLs the request was redirected to in order, the
+// last one answered with the response, nil when no redirect was followed.
+func (resp *Response) Redirects() []string {
+	if resp.request == nil {
+		return nil
+	}
+	return resp.request.redirects
+}
+
+// Follow issues the request the redirect response points to with the same
+// client, which is useful when redirects are not followed automatically.
+// A relative Location is resolved against the request URL. 301, 302 and 303
+// change the method to GET and drop the body (HEAD stays HEAD), 307 and 308
+// keep the method and send the body again, which requires a replayable body.
+// Headers are carried over, except for credentials when the host changes,
+// the cookies of the client jar are applied by the client. The hop goes
+// through the client hooks, auth provider, cache and limits as Do does,
+// with the settings of the original request.
+func (resp *Response) Follow() *Response {
+	if resp.err != nil {
+		return &Response{err: resp.err, request: resp.request}
+	}
+	if resp.Response == nil || resp.request == nil {
+		return &Response{err: ErrorNotRedirect}
+	}
+
+	location := resp.Header.Get("Location")
+	if location == "" {
+		return &Response{err: ErrorNoLocation, request: resp.request}
+	}
+
+	prev := resp.Response.Request
+	target, err := prev.URL.Parse(location)
+	if err != nil {
+		return &Response{err: err, request: resp.request}
+	}
+
+	method := prev.Method
+	keepBody := false
+	switch resp.StatusCode {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther:
+		if method != http.MethodHead {
+			method = http.MethodGet
+		}
+	case http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		keepBody = true
+	default:
+		return &Response{
+			err:     fmt.Errorf("%w: %s", ErrorNotRedirect, resp.Status),
+			request: resp.request,
+		}
+	}
+
+	var body io.ReadCloser
+	if keepBody && prev.ContentLength != 0 && prev.Body != nil && prev.Body != http.NoBody {
+		if prev.GetBody == nil {
+			return &Response{err: ErrorBodyNotReplay, request: resp.request}
+		}
+		if body, err = prev.GetBody(); err != nil {
+			return &Response{err: err, request: resp.request}
+		}
+	}
+
+	req, err := http.NewRequestWithContext(resp.request.context(), method, target.String(), body)
+	if err != nil {
+		return &Response{err: err, request: resp.request}
+	}
+	req.Header = prev.Header.Clone()
+	if body != nil {
+		req.GetBody = prev.GetBody
+		req.ContentLength = prev.ContentLength
+	} else {
+		req.Header.Del("Content-Type")
+		req.Header.Del("Content-Length")
+	}
+	if target.Host != prev.URL.Host {
+		for _, key := range []string{"Authorization", "Www-Authenticate", "Cookie", "Cookie2"} {
+			req.Header.Del(key)
+		}
+	}
+
+	next := resp.request.Clone()
+	next.Request = req
+	next.method, next.uri = method, req.URL.String()
+	return next.dispatch()
+}
@@ -0,0 +1,185 @@
+package www
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestFollow(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/see-other":
+			w.Header().Set("Location", "final")
+			w.WriteHeader(http.StatusSeeOther)
+		case "/temporary":
+			w.Header().Set("Location", "/final")
+			w.WriteHeader(http.StatusTemporaryRedirect)
+		case "/none":
+			w.WriteHeader(http.StatusFound)
+		case "/to-auth":
+			w.Header().Set("Location", "/auth")
+			w.WriteHeader(http.StatusFound)
+		case "/auth":
+			w.Write([]byte(r.Header.Get("Authorization")))
+		default:
+			body, _ := io.ReadAll(r.Body)
+			fmt.Fprintf(w, "%s %s %s", r.Method, r.URL.Path, body)
+		}
+	}))
+	defer srv.Close()
+
+	cl := NewClient(&http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	})
+
+	t.Run("303", func(t *testing.T) {
+		resp := NewRequest(cl).JSON(map[string]int{"n": 1}).Post(srv.URL + "/see-other")
+		if resp.StatusCode != http.StatusSeeOther {
+			t.Fatalf("StatusCode:got %d, want 303", resp.StatusCode)
+		}
+		next := resp.Follow()
+		if next.Error() != nil {
+			t.Fatalf("%v", next.Error())
+		}
+		if got := next.Text(); got != "GET /final " {
+			t.Errorf("got %q, want %q", got, "GET /final ")
+		}
+	})
+
+	t.Run("307", func(t *testing.T) {
+		resp := NewRequest(cl).JSON(map[string]int{"n": 1}).Post(srv.URL + "/temporary")
+		next := resp.Follow()
+		if next.Error() != nil {
+			t.Fatalf("%v", next.Error())
+		}
+		if got := next.Text(); got != `POST /final {"n":1}` {
+			t.Errorf("got %q, want %q", got, `POST /final {"n":1}`)
+		}
+	})
+
+	t.Run("PIPELINE", func(t *testing.T) {
+		var before, after int
+		hooked := NewClient().WithNoRedirects().
+			WithAuthProvider(func(r *Request) error {
+				r.Header.Set("Authorization", "Bearer token")
+				return nil
+			}).
+			OnBeforeRequest(func(*Request) error { before++; return nil }).
+			OnAfterResponse(func(*Request, *Response) error { after++; return nil })
+
+		next := NewRequest(hooked).Get(srv.URL + "/to-auth").Follow()
+		if got := next.Text(); got != "Bearer token" {
+			t.Errorf("Authorization:got %q, want %q", got, "Bearer token")
+		}
+		if before != 2 || after != 2 {
+			t.Errorf("hooks:got %d before, %d after, want 2, 2", before, after)
+		}
+	})
+
+	t.Run("NO LOCATION", func(t *testing.T) {
+		next := NewRequest(cl).Get(srv.URL + "/none").Follow()
+		if !errors.Is(next.Error(), ErrorNoLocation) {
+			t.Errorf("Error:got %v, want %v", next.Error(), ErrorNoLocation)
+		}
+	})
+}
+
+func TestRedirectLoop(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ping":
+			http.Redirect(w, r, "/pong", http.StatusFound)
+		case "/pong":
+			http.Redirect(w, r, "/ping", http.StatusFound)
+		case "/form":
+			if r.Method == http.MethodPost {
+				http.Redirect(w, r, "/form", http.StatusSeeOther)
+			}
+		}
+	}))
+	defer srv.Close()
+
+	resp := NewRequest(NewClient()).Get(srv.URL + "/ping")
+	if !errors.Is(resp.Error(), ErrRedirectLoop) {
+		t.Errorf("Error:got %v, want %v", resp.Error(), ErrRedirectLoop)
+	}
+
+	// the same URL with another method is not a loop
+	resp = NewRequest(NewClient()).Post(srv.URL + "/form")
+	if resp.Error() != nil || resp.StatusCode != http.StatusOK {
+		t.Errorf("POST-redirect-GET:got %v, %v", resp.Error(), resp.Status)
+	}
+}
+
+func TestRedirectPolicy(t *testing.T) {
+
+	// /3 redirects to /2, /1 and /0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if n, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/")); n > 0 {
+			http.Redirect(w, r, "/"+strconv.Itoa(n-1), http.StatusFound)
+			return
+		}
+		w.Write([]byte("done"))
+	}))
+	defer srv.Close()
+
+	t.Run("CHAIN", func(t *testing.T) {
+		resp := NewRequest(NewClient()).Get(srv.URL + "/3")
+		want := []string{srv.URL + "/2", srv.URL + "/1", srv.URL + "/0"}
+		if resp.Text() != "done" || fmt.Sprint(resp.Redirects()) != fmt.Sprint(want) {
+			t.Errorf("Redirects:got %v, want %v", resp.Redirects(), want)
+		}
+		if resp := NewRequest(NewClient()).Get(srv.URL + "/0"); resp.Redirects() != nil {
+			t.Errorf("no redirect:got %v, want nil", resp.Redirects())
+		}
+	})
+
+	t.Run("MAX", func(t *testing.T) {
+		resp := NewRequest(NewClient().WithMaxRedirects(2)).Get(srv.URL + "/3")
+		if !errors.Is(resp.Error(), ErrTooManyRedirects) {
+			t.Errorf("client:got %v, want %v", resp.Error(), ErrTooManyRedirects)
+		}
+		resp = NewRequest(NewClient().WithMaxRedirects(2)).MaxRedirects(3).Get(srv.URL + "/3")
+		if resp.Error() != nil || resp.Text() != "done" {
+			t.Errorf("request:got %v", resp.Error())
+		}
+	})
+
+	t.Run("NONE", func(t *testing.T) {
+		for name, resp := range map[string]*Response{
+			"client":  NewRequest(NewClient().WithNoRedirects()).Get(srv.URL + "/3"),
+			"request": NewRequest(NewClient()).NoRedirects().Get(srv.URL + "/3"),
+		} {
+			if resp.Error() != nil || resp.StatusCode != http.StatusFound || resp.Redirects() != nil {
+				t.Errorf("%s:got %v, %v after %v", name, resp.Error(), resp.Status, resp.Redirects())
+			}
+		}
+	})
+
+	t.Run("POLICY", func(t *testing.T) {
+		resp := NewRequest(NewClient()).
+			RedirectPolicy(func(req *http.Request, via []*http.Request) error {
+				if req.URL.Path == "/1" {
+					return http.ErrUseLastResponse
+				}
+				return nil
+			}).
+			Get(srv.URL + "/3")
+		if resp.StatusCode != http.StatusFound || resp.Header.Get("Location") != "/1" {
+			t.Errorf("got %v to %q", resp.Status, resp.Header.Get("Location"))
+		}
+		if fmt.Sprint(resp.Redirects()) != "["+srv.URL+"/2]" {
+			t.Errorf("Redirects:got %v", resp.Redirects())
+		}
+	})
+}
@@ -2,28 +2,104 @@ package www
 
 import (
 	"bytes"
+	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
-	//"fmt"
-	"mime/multipart"
+	"mime"
+	"net"
 	"net/http"
+	"net/http/httptrace"
+	"net/textproto"
 	"net/url"
 	"os"
-	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
+	"text/template"
+	"time"
 )
 
 var ErrorEmptyListValues = errors.New("an empty list of values is passed to create multipart content")
 
+var ErrorNotSeekable = errors.New("the body reader is not seekable")
+
+var (
+	ErrorEmptyURL               = errors.New("the request URL is empty")
+	ErrorBodyNotAllowed         = errors.New("the request method does not allow a body")
+	ErrorConflictingContentType = errors.New("conflicting content types")
+)
+
+// methods that conventionally have no body
+var noBodyMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodTrace:   true,
+	http.MethodOptions: true,
+	http.MethodConnect: true,
+}
+
 type Request struct {
 	*http.Request
 	client  *StandardClient
+	ctx     context.Context
 	err     error
 	body    io.Reader
 	params  string
 	mime    string
 	cookies []*http.Cookie
+	method  string
+	uri     string
+	// set by ContentType, wins over mime
+	contentType string
+	// WithHeader, WithBasicAuth, ..., before the headers passed to Do
+	headers http.Header
+	onRetry func(attempt int, resp *http.Response, err error)
+	// RetryOnErrorMatch
+	errorPatterns  []string
+	retryDeadline  time.Duration
+	idempotencyKey string
+	generatedKey   bool
+	accept         string
+	noCompress     bool
+	baseURL        string
+	pathParams     map[string]string
+	sign           func(req *http.Request, body []byte) error
+	values         []contextValue
+	maxUpload      int64
+	tags           map[string]string
+	operation      string
+	resume         int
+	closeConn      bool
+	// from writing the request to the response headers
+	responseTimeout time.Duration
+	timeout         time.Duration
+	retry           *RetryPolicy
+	onUpload        func(sent, total int64)
+	maxRedirects    int
+	redirectPolicy  func(req *http.Request, via []*http.Request) error
+	redirects       []string
+	cacheKey        string
+	cacheEntry      *CachedResponse
+	on1xx           func(code int, header http.Header)
+	onDNS           func(host string, addrs []net.IPAddr, err error)
+	// query spaces as %20 instead of +
+	percentSpaces bool
+	// Content-Encoding of the request body
+	compress  string
+	signQuery func(params url.Values) url.Values
+	// CachedValidators
+	etag         string
+	lastModified time.Time
+	cached       []byte
+	// appended to X-Forwarded-For and Forwarded
+	forwardedFor []string
+	forwarded    []string
+	// replayable bodies
+	length  int64
+	getBody func() (io.ReadCloser, error)
 }
 
 func NewRequest(client *StandardClient) *Request {
@@ -52,6 +128,49 @@ func (r Request) Cookies() (out []*http.Cookie) {
 	return out
 }
 
+// Validate checks the request for common mistakes: a body on a method that
+// conventionally has none, an empty URL and a Content-Type header that
+// conflicts with the type inferred from the body. The method and the URL
+// are known once the request was prepared (see Prepare) or passed to Do,
+// a strict client calls Validate there automatically.
+func (r *Request) Validate() error {
+	return r.validate()
+}
+
+func (r *Request) validate(headers ...http.Header) error {
+	if r.err != nil {
+		return r.err
+	}
+
+	if r.method != "" {
+		if r.uri == "" {
+			return fmt.Errorf("%w: pass an absolute URL to %s", ErrorEmptyURL, r.method)
+		}
+		if _, err := url.Parse(r.uri); err != nil {
+			return err
+		}
+		if r.body != nil && noBodyMethods[r.method] {
+			return fmt.Errorf(
+				"%w: %s request has a body, use POST, PUT or PATCH to send data",
+				ErrorBodyNotAllowed, r.method)
+		}
+	}
+
+	if r.mimeType() != "" && len(headers) > 0 {
+		if ct := headers[0].Get("Content-Type"); ct != "" {
+			inferred, _, _ := mime.ParseMediaType(r.mimeType())
+			explicit, _, _ := mime.ParseMediaType(ct)
+			if inferred != explicit {
+				return fmt.Errorf(
+					"%w: the body is %s, but the Content-Type header is %s",
+					ErrorConflictingContentType, inferred, explicit)
+			}
+		}
+	}
+
+	return nil
+}
+
 func (r *Request) SetCookies(cookies ...*http.Cookie) *Request {
 	r.cookies = cookies
 	return r
@@ -68,28 +187,344 @@ func (r *Request) prepareRequest(
 
 	var err error
 
-	body, ok := r.body.(io.ReadCloser)
-	if !ok && r.body != nil {
-		body = io.NopCloser(r.body)
+	// http.NewRequestWithContext makes *bytes.Buffer, *bytes.Reader and
+	// *strings.Reader bodies replayable
+	body := r.body
+	if r.getBody != nil {
+		if body, err = r.getBody(); err != nil {
+			r.err = err
+			return
+		}
 	}
 
-	r.Request, err = http.NewRequest(method, uri, body)
+	getBody, length := r.getBody, r.length
+	encoding := r.encoding()
+	if encoding != "" && body != nil {
+		if getBody == nil && isBuffered(body) {
+			if body, err = r.compressBytes(body); err != nil {
+				r.err = err
+				return
+			}
+		} else {
+			body = r.compressStream(body)
+			if next := getBody; next != nil {
+				getBody = func() (io.ReadCloser, error) {
+					body, err := next()
+					if err != nil {
+						return nil, err
+					}
+					return r.compressStream(body), nil
+				}
+			}
+			length = -1
+		}
+	}
+
+	if uri, err = r.resolve(uri); err != nil {
+		r.err = err
+		return
+	}
+	r.Request, err = http.NewRequestWithContext(r.context(), method, uri, body)
 	if err != nil {
 		r.err = err
 		return
 	}
+	for key, val := range r.client.DefaultHeaders {
+		r.Request.Header[key] = append([]string(nil), val...)
+	}
+	if getBody != nil {
+		r.Request.GetBody = getBody
+		r.Request.ContentLength = length
+	}
+	if encoding != "" && body != nil {
+		r.Request.Header.Set("Content-Encoding", encoding)
+	}
+	if cr, ok := r.body.(*chanReader); ok {
+		cr.ctx = r.Request.Context()
+	}
+	if r.body == nil {
+		r.Request.GetBody = func() (io.ReadCloser, error) {
+			return http.NoBody, nil
+		}
+	}
+	if r.maxUpload > 0 {
+		r.limitUpload()
+		if r.err != nil {
+			return
+		}
+	}
 
-	r.Request.URL.RawQuery = r.params
-	if r.mime != "" {
-		r.Request.Header.Set("Content-Type", r.mime)
+	query := r.params
+	if r.signQuery != nil {
+		params, err := url.ParseQuery(query)
+		if err != nil {
+			r.err = err
+			return
+		}
+		query = r.signQuery(params).Encode()
+	}
+	r.Request.URL.RawQuery = query
+	if r.percentSpaces {
+		// url.Values encodes a literal + as %2B
+		r.Request.URL.RawQuery = strings.ReplaceAll(query, "+", "%20")
+	}
+	r.client.mapHost(r.Request)
+	if mime := r.mimeType(); mime != "" {
+		r.Request.Header.Set("Content-Type", mime)
+	}
+	if r.client.acceptEncoding != "" {
+		r.Request.Header.Set("Accept-Encoding", r.client.acceptEncoding)
+	}
+	if r.client.acceptCharset != "" {
+		r.Request.Header.Set("Accept-Charset", r.client.acceptCharset)
+	}
+	if accept := r.acceptHeader(); accept != "" {
+		r.Request.Header.Set("Accept", accept)
 	}
 
+	r.prepareIdempotencyKey()
+	for key, val := range r.headers {
+		r.Request.Header[key] = append([]string(nil), val...)
+	}
 	if len(headers) > 0 {
 		for key, val := range headers[0] {
 			r.Request.Header.Set(key, val[0])
 		}
 	}
+	r.prepareForwarded()
+	r.prepareValidators()
+	r.stripHopByHop()
+	if r.closeConn {
+		r.Request.Close = true
+	}
+}
+
+// BaseURL resolves the relative URIs of this request against u instead of
+// the BaseURL of the client, the headers and auth of the client still
+// apply.
+func (r *Request) BaseURL(u string) *Request {
+	r.baseURL = u
+	return r
+}
+
+// WithPathParams fills the {name} placeholders of the request URI with
+// the path-escaped values of params, e.g. Get("/users/{id}"). Several
+// calls add up; placeholders without a value are sent as is.
+func (r *Request) WithPathParams(params map[string]string) *Request {
+	if r.pathParams == nil {
+		r.pathParams = make(map[string]string, len(params))
+	}
+	for name, val := range params {
+		r.pathParams[name] = val
+	}
+	return r
+}
+
+func (r *Request) resolve(uri string) (string, error) {
+	if len(r.pathParams) > 0 {
+		pairs := make([]string, 0, 2*len(r.pathParams))
+		for name, val := range r.pathParams {
+			pairs = append(pairs, "{"+name+"}", url.PathEscape(val))
+		}
+		uri = strings.NewReplacer(pairs...).Replace(uri)
+	}
+	if r.baseURL != "" {
+		return resolveURI(r.baseURL, uri)
+	}
+	return r.client.resolve(uri)
+}
 
+// WithHeader sets a header of the request, a header of the same name
+// passed to Do wins.
+func (r *Request) WithHeader(key, value string) *Request {
+	if r.headers == nil {
+		r.headers = make(http.Header)
+	}
+	r.headers.Set(key, value)
+	return r
+}
+
+// Accept sets the Accept header of the request, several media types are
+// listed in the order of preference with quality values. It wins over the
+// client defaults (see WithAccept), WithHeader and the headers passed to
+// Do win over it.
+func (r *Request) Accept(mediaTypes ...string) *Request {
+	r.accept = qualityList(mediaTypes)
+	return r
+}
+
+func (r *Request) acceptHeader() string {
+	if r.accept != "" {
+		return r.accept
+	}
+	if r.client.jsonAccept && r.mime == "application/json" {
+		return "application/json"
+	}
+	return r.client.accept
+}
+
+// WithBasicAuth sets the Authorization header for HTTP basic
+// authentication.
+func (r *Request) WithBasicAuth(username, password string) *Request {
+	credentials := base64.StdEncoding.EncodeToString([]byte(username + ":" + password))
+	return r.WithHeader("Authorization", "Basic "+credentials)
+}
+
+// WithBearerToken sets the Authorization header to Bearer token.
+func (r *Request) WithBearerToken(token string) *Request {
+	return r.WithHeader("Authorization", "Bearer "+token)
+}
+
+// WithContext sets the context of the request: cancelling it or reaching
+// its deadline aborts the request and the reading of its body, without
+// changing the Timeout of the shared client. Do fails right away when
+// the context is already done. It hides the WithContext method of the
+// embedded http.Request.
+func (r *Request) WithContext(ctx context.Context) *Request {
+	r.ctx = ctx
+	return r
+}
+
+// WithValue attaches val to the context of the request under key, for
+// the transports and hooks that see the http.Request, as
+// context.WithValue does. The values are layered over the WithContext
+// context whichever is called first.
+func (r *Request) WithValue(key, val interface{}) *Request {
+	r.values = append(r.values, contextValue{key, val})
+	return r
+}
+
+type contextValue struct {
+	key, val interface{}
+}
+
+func (r *Request) context() context.Context {
+	ctx := r.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	for _, v := range r.values {
+		ctx = context.WithValue(ctx, v.key, v.val)
+	}
+	if r.operation != "" {
+		ctx = context.WithValue(ctx, operationKey{}, r.operation)
+	}
+	return ctx
+}
+
+// Close makes the client close the connection after this request
+// (Connection: close) instead of keeping it for reuse, keep-alives of
+// the other requests are not affected. It hides the Close field of the
+// embedded http.Request, use r.Request.Close to read it.
+func (r *Request) Close() *Request {
+	r.closeConn = true
+	return r
+}
+
+// ContentType sets the Content-Type of the body, it wins over the type
+// inferred by Json, WithForm, etc. regardless of the call order.
+func (r *Request) ContentType(contentType string) *Request {
+	r.contentType = contentType
+	return r
+}
+
+// OnRetry registers a callback invoked before each retry sleep with the
+// attempt number and the response or the error that caused the retry.
+// It runs synchronously on the retry path, so a slow callback delays
+// the retry.
+func (r *Request) OnRetry(fn func(attempt int, resp *http.Response, err error)) *Request {
+	r.onRetry = fn
+	return r
+}
+
+// RetryDeadline bounds the time spent on the attempts and the waits of
+// the retry policy, the last result is returned when the next wait would
+// end past d. An attempt in flight is not cut, ResponseTimeout bounds one
+// attempt.
+func (r *Request) RetryDeadline(d time.Duration) *Request {
+	r.retryDeadline = d
+	return r
+}
+
+// RetryOnErrorMatch makes an error retryable when its message contains
+// any of the patterns, e.g. "connection reset by peer" or "EOF". It is
+// a last resort for transport errors that are not typed, prefer matching
+// the errors with errors.Is/errors.As. It only applies when retries are
+// enabled and only to methods that may be retried.
+func (r *Request) RetryOnErrorMatch(patterns ...string) *Request {
+	r.errorPatterns = append(r.errorPatterns, patterns...)
+	return r
+}
+
+// errorMatches reports whether err matches a RetryOnErrorMatch pattern.
+func (r *Request) errorMatches(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := err.Error()
+	for _, pattern := range r.errorPatterns {
+		if strings.Contains(msg, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// On1xx registers an advanced hook called for every informational
+// response received before the final one, e.g. 103 Early Hints with
+// the resources to preload or 100 Continue.
+func (r *Request) On1xx(fn func(code int, header http.Header)) *Request {
+	r.on1xx = fn
+	return r
+}
+
+// OnDNS registers a hook called with the addresses a host name resolved
+// to, or the lookup error, for every connection the request dials. It is
+// not called for IP literals and reused connections.
+func (r *Request) OnDNS(fn func(host string, addrs []net.IPAddr, err error)) *Request {
+	r.onDNS = fn
+	return r
+}
+
+// MaxUploadSize aborts the request with ErrUploadTooLarge when the body
+// is larger than n bytes, a body of unknown length fails while it is sent.
+func (r *Request) MaxUploadSize(n int64) *Request {
+	r.maxUpload = n
+	return r
+}
+
+func (r *Request) limitUpload() {
+	if r.Request.ContentLength > r.maxUpload {
+		r.err = fmt.Errorf("%w: %d bytes, the limit is %d",
+			ErrUploadTooLarge, r.Request.ContentLength, r.maxUpload)
+		return
+	}
+
+	limit := func(body io.ReadCloser) io.ReadCloser {
+		return struct {
+			io.Reader
+			io.Closer
+		}{&maxBytesReader{body, r.maxUpload, ErrUploadTooLarge}, body}
+	}
+	if r.Request.Body != nil && r.Request.Body != http.NoBody {
+		r.Request.Body = limit(r.Request.Body)
+	}
+	if getBody := r.Request.GetBody; getBody != nil {
+		r.Request.GetBody = func() (io.ReadCloser, error) {
+			body, err := getBody()
+			if err != nil {
+				return nil, err
+			}
+			return limit(body), nil
+		}
+	}
+}
+
+func (r *Request) mimeType() string {
+	if r.contentType != "" {
+		return r.contentType
+	}
+	return r.mime
 }
 
 func (r *Request) Get(uri string, headers ...http.Header) *Response {
@@ -124,32 +559,188 @@ func (r *Request) Options(uri string) *Response {
 	return r.Do(http.MethodOptions, uri) // no body
 }
 
-func (r *Request) Connect(uri string) *Response {
-	return r.Do(http.MethodConnect, uri) // no body
-}
-
 func (r *Request) Do(method string, uri string, headers ...http.Header) *Response {
-	var err error
-
 	defer closeReader(r.body)
 
 	if r.err != nil {
-		return &Response{nil, r.err, nil}
+		return &Response{err: r.err}
+	}
+	if r.client.err != nil {
+		return &Response{err: r.client.err}
+	}
+
+	r.method, r.uri = method, uri
+	if err := r.context().Err(); err != nil {
+		return &Response{err: fmt.Errorf("%s %s: %w", method, uri, err)}
+	}
+	if r.client.strict {
+		if err := r.validate(headers...); err != nil {
+			return &Response{err: err}
+		}
 	}
 
 	r.prepareRequest(method, uri, headers...)
 	r.prepareCookies()
+	return r.dispatch()
+}
+
+// dispatch sends the prepared http.Request through the hooks, the cache
+// and the signature, for Do and Follow.
+func (r *Request) dispatch() *Response {
+	if r.err == nil {
+		r.runBeforeRequest()
+	}
+	if r.err == nil {
+		if resp := r.lookupCache(); resp != nil {
+			r.runAfterResponse(resp)
+			return resp
+		}
+	}
+	if r.err == nil && r.sign != nil {
+		r.signBody()
+	}
 	if r.err != nil {
-		return &Response{nil, r.err, nil}
+		return &Response{err: r.err}
+	}
+
+	resp := r.send()
+	r.runAfterResponse(resp)
+	return resp
+}
+
+// send executes the prepared request.
+func (r *Request) send() *Response {
+	var release func(*Response)
+	if r.timeout > 0 {
+		release = r.withTimeout()
+	}
+	if r.on1xx != nil {
+		r.Request = r.Request.WithContext(httptrace.WithClientTrace(r.Request.Context(),
+			&httptrace.ClientTrace{
+				Got1xxResponse: func(code int, header textproto.MIMEHeader) error {
+					r.on1xx(code, http.Header(header))
+					return nil
+				},
+			}))
+	}
+	if r.onDNS != nil {
+		var host string
+		r.Request = r.Request.WithContext(httptrace.WithClientTrace(r.Request.Context(),
+			&httptrace.ClientTrace{
+				DNSStart: func(info httptrace.DNSStartInfo) {
+					host = info.Host
+				},
+				DNSDone: func(info httptrace.DNSDoneInfo) {
+					r.onDNS(host, info.Addrs, info.Err)
+				},
+			}))
 	}
 
-	resp, err := r.client.Do(r.Request)
+	prepared := r.Request
+	start := r.client.now()
+	var resp *http.Response
+	var err error
+	for attempt := 0; ; attempt++ {
+		if resp, err = r.roundTrip(prepared, attempt); !r.shouldRetry(attempt, resp, err) {
+			break
+		}
+		if !replayable(prepared) {
+			err = notRetried(resp, err)
+			break
+		}
+		wait := r.retryPolicy().wait(attempt+1, resp, r.client.now())
+		if r.retryDeadline > 0 && r.client.now().Add(wait).Sub(start) > r.retryDeadline {
+			break
+		}
+		if r.onRetry != nil {
+			r.onRetry(attempt+1, resp, err)
+		}
+		if resp != nil {
+			drainBody(resp.Body)
+		}
+		if serr := r.client.sleep(prepared.Context(), wait); serr != nil {
+			resp, err = nil, serr
+			break
+		}
+	}
+	if err == nil {
+		r.resumeBody(resp)
+		for _, transform := range r.client.bodyTransforms {
+			resp.Body = transform(resp.Body)
+		}
+	}
+	if errors.Is(err, ErrUploadTooLarge) {
+		r.err = err
+	}
 
-	return &Response{
+	response := &Response{
 		Response: resp,
 		err:      err,
 		content:  nil,
+		request:  r,
+	}
+	response.countBody()
+	response.decodeBody()
+	response.storeCache()
+	response.useCached()
+	if release != nil {
+		release(response)
+	}
+	return response
+}
+
+// roundTrip sends one attempt of the prepared request, the retries get
+// a copy of it with the body replayed.
+func (r *Request) roundTrip(prepared *http.Request, attempt int) (*http.Response, error) {
+	r.Request = prepared
+	if attempt > 0 {
+		r.Request = prepared.Clone(prepared.Context())
+		if prepared.GetBody != nil {
+			body, err := prepared.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			r.Request.Body = body
+		}
+	}
+	r.trackUpload()
+	var release func()
+	if len(r.client.limits) > 0 {
+		var err error
+		if release, err = r.acquireLimits(); err != nil {
+			return nil, err
+		}
+	}
+
+	r.Request = r.client.traceConn(r.Request)
+	var finish func(*http.Response, error) error
+	if r.responseTimeout > 0 {
+		finish = r.withResponseTimeout()
 	}
+	var logExchange func(*http.Response, error)
+	if r.client.debug {
+		logExchange = r.debugTrace()
+	}
+	start := r.client.now()
+	r.redirects = nil
+	resp, err := r.client.httpClient(r).Do(r.Request)
+	if finish != nil {
+		err = finish(resp, err)
+	}
+	err = protocolMismatch(r.Request.URL, err)
+	if logExchange != nil {
+		logExchange(resp, err)
+	}
+	if release != nil {
+		// the body of a 101 is the connection, left as it is
+		if err != nil || resp.StatusCode == http.StatusSwitchingProtocols {
+			release()
+		} else {
+			resp.Body = &releaseBody{resp.Body, release}
+		}
+	}
+	r.emitMetric(start, resp, err)
+	return resp, err
 }
 
 func (r *Request) With(params *url.Values, data *url.Values) *Request {
@@ -164,12 +755,115 @@ func (r *Request) WithQuery(params *url.Values) *Request {
 	return r
 }
 
+// Query merges query parameters from url.Values, *url.Values,
+// map[string]string, map[string][]string and structs tagged with `url`
+// (see encodeStruct) into the query of the request. The parameters of
+// a later argument replace the ones of the same name set before.
+func (r *Request) Query(sources ...interface{}) *Request {
+	query, err := url.ParseQuery(r.params)
+	if err != nil {
+		r.err = err
+		return r
+	}
+
+	for _, source := range sources {
+		var values url.Values
+		switch v := source.(type) {
+		case url.Values:
+			values = v
+		case *url.Values:
+			values = *v
+		case map[string][]string:
+			values = v
+		case map[string]string:
+			values = make(url.Values, len(v))
+			for key, val := range v {
+				values.Set(key, val)
+			}
+		default:
+			if values, err = encodeStruct(source, "url", r.client.timeFormat); err != nil {
+				r.err = err
+				return r
+			}
+		}
+		for key, val := range values {
+			query[key] = append([]string(nil), val...)
+		}
+	}
+
+	r.params = query.Encode()
+	return r
+}
+
+// WithQueryStruct merges the fields of the struct v, tagged with `url`,
+// into the query as Query does, anything else than a struct (or a
+// pointer to one) fails with ErrorUnsupportedValues.
+func (r *Request) WithQueryStruct(v interface{}) *Request {
+	values, err := encodeStruct(v, "url", r.client.timeFormat)
+	if err != nil {
+		r.err = err
+		return r
+	}
+	return r.Query(values)
+}
+
+// SignQuery calls sign with the complete query parameters right before
+// they are encoded, the returned values are sent instead, e.g. with a
+// signature parameter computed over the others for presigned URLs.
+func (r *Request) SignQuery(sign func(params url.Values) url.Values) *Request {
+	r.signQuery = sign
+	return r
+}
+
+// PercentEncodeSpaces encodes spaces of the query as %20 (RFC 3986)
+// instead of + (form encoding), for servers and signature schemes that
+// require it.
+func (r *Request) PercentEncodeSpaces() *Request {
+	r.percentSpaces = true
+	return r
+}
+
 func (r *Request) WithForm(data *url.Values) *Request {
 	r.mime = "application/x-www-form-urlencoded"
 	r.body = strings.NewReader(data.Encode())
 	return r
 }
 
+// WithFormStruct sends the struct v as a form, its fields are encoded as
+// the query structs of Query are, named by `form` tags:
+// `form:"name,omitempty"`, `form:"since,unix"`.
+func (r *Request) WithFormStruct(v interface{}) *Request {
+	data, err := encodeStruct(v, "form", r.client.timeFormat)
+	if err != nil {
+		r.err = err
+		return r
+	}
+	return r.WithForm(&data)
+}
+
+// FormMap sends the flat map as a form, pass true to leave out the fields
+// with an empty value. WithForm handles fields with several values.
+func (r *Request) FormMap(m map[string]string, omitEmpty ...bool) *Request {
+	data := make(url.Values, len(m))
+	for key, val := range m {
+		if val == "" && len(omitEmpty) > 0 && omitEmpty[0] {
+			continue
+		}
+		data.Set(key, val)
+	}
+	return r.WithForm(&data)
+}
+
+// FormMulti sends the map as a form, a field with several values is
+// encoded as repeated key=v1&key=v2 pairs.
+func (r *Request) FormMulti(m map[string][]string) *Request {
+	data := make(url.Values, len(m))
+	for key, values := range m {
+		data[key] = append([]string(nil), values...)
+	}
+	return r.WithForm(&data)
+}
+
 func (r *Request) Json(data interface{}) *Request {
 
 	body, err := json.Marshal(data)
@@ -186,108 +880,240 @@ func (r *Request) JSON(data interface{}) *Request {
 	return r.Json(data)
 }
 
-func (r *Request) WithFile(reader io.Reader) *Request {
-	r.mime = "binary/octet-stream"
-	r.body = reader
-	return r
-}
+// ErrorInvalidJSON is set by JSONTemplate when the rendered body is not JSON.
+var ErrorInvalidJSON = errors.New("the rendered body is not valid JSON")
 
-func (r *Request) AttachFile(reader io.Reader, contentType ...string) *Request {
-	var err error
-	var fileName string
-	var part io.Writer
-
-	if f, ok := reader.(*os.File); ok {
-		defer closeReader(reader)
-		fileName = filepath.Base(f.Name())
-	} else {
+// JSONTemplate renders the text/template tmpl with data and sends the
+// result as a JSON body, for mostly static payloads. text/template does
+// not escape the values, the json function of the template encodes one:
+// {"name": {{json .Name}}}. Template and JSON errors are set on the request.
+func (r *Request) JSONTemplate(tmpl string, data interface{}) *Request {
+	t, err := template.New("json").Funcs(template.FuncMap{
+		"json": func(v interface{}) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+	}).Parse(tmpl)
+	if err != nil {
 		r.err = err
 		return r
 	}
 
-	body := new(bytes.Buffer)
-	writer := multipart.NewWriter(body)
-
-	if part, err = CreateFormFile(
-		writer, "file", fileName, contentType...); err != nil {
+	var body bytes.Buffer
+	if err = t.Execute(&body, data); err != nil {
 		r.err = err
 		return r
 	}
+	if !json.Valid(body.Bytes()) {
+		r.err = fmt.Errorf("%w: %s", ErrorInvalidJSON, body.String())
+		return r
+	}
 
-	_, err = io.Copy(part, reader)
+	r.mime = "application/json"
+	r.body = bytes.NewReader(body.Bytes())
+	r.getBody = nil
+	return r
+}
+
+// NoBody drops the body and the body type set by a previous call, a POST,
+// PUT or PATCH request is then sent with Content-Length: 0.
+func (r *Request) NoBody() *Request {
+	r.body = nil
+	r.mime = ""
+	r.length = 0
+	r.getBody = nil
+	return r
+}
+
+// WithFile streams reader as the body. A regular *os.File is sent like
+// WithSeeker, so it is replayed on redirects instead of sending an empty
+// body, the file is closed by Do once the request is done.
+func (r *Request) WithFile(reader io.Reader) *Request {
+	if f, ok := reader.(*os.File); ok {
+		if fi, err := f.Stat(); err == nil && fi.Mode().IsRegular() {
+			return r.WithSeeker(f, "binary/octet-stream")
+		}
+	}
+	r.mime = "binary/octet-stream"
+	r.body = reader
+	return r
+}
 
+// WithSeeker streams rs as the body with a known Content-Length, the body
+// is sent from the current position of rs and is replayed by seeking back
+// to it on redirects. A reader that fails to seek is reported by Do
+// as ErrorNotSeekable.
+func (r *Request) WithSeeker(rs io.ReadSeeker, contentType string) *Request {
+	start, err := rs.Seek(0, io.SeekCurrent)
+	if err == nil {
+		var end int64
+		if end, err = rs.Seek(0, io.SeekEnd); err == nil {
+			_, err = rs.Seek(start, io.SeekStart)
+		}
+		r.length = end - start
+	}
 	if err != nil {
-		r.err = err
+		r.err = fmt.Errorf("%w: %v", ErrorNotSeekable, err)
 		return r
 	}
 
-	r.mime = writer.FormDataContentType()
-	writer.Close()
-	r.body = body
+	r.mime = contentType
+	r.body = rs
+	r.getBody = func() (io.ReadCloser, error) {
+		if _, err := rs.Seek(start, io.SeekStart); err != nil {
+			return nil, err
+		}
+		// the reader is closed by Do once the request is done
+		return io.NopCloser(rs), nil
+	}
+	return r
+}
 
+// WithChannel streams the chunks received on ch as a chunked body until
+// ch is closed, an expired request context stops reading it. The body
+// cannot be sent again, so it is not replayed on redirects or retries.
+func (r *Request) WithChannel(ch <-chan []byte, contentType string) *Request {
+	r.mime = contentType
+	r.body = &chanReader{ch: ch}
 	return r
 }
 
-func (r *Request) AttachFiles(files map[string][]interface{}) *Request {
-	var (
-		err         error
-		fileName    string
-		contentType string
-		part        io.Writer
-	)
+type chanReader struct {
+	ch  <-chan []byte
+	buf []byte
+	ctx context.Context
+}
 
-	body := new(bytes.Buffer)
-	writer := multipart.NewWriter(body)
+func (c *chanReader) Read(p []byte) (int, error) {
+	for len(c.buf) == 0 {
+		var done <-chan struct{}
+		if c.ctx != nil {
+			done = c.ctx.Done()
+		}
+		select {
+		case chunk, ok := <-c.ch:
+			if !ok {
+				return 0, io.EOF
+			}
+			c.buf = chunk
+		case <-done:
+			return 0, c.ctx.Err()
+		}
+	}
+	n := copy(p, c.buf)
+	c.buf = c.buf[n:]
+	return n, nil
+}
 
-	var closeReaders []io.Reader
+// WithWriterTo streams the body written by wt.WriteTo, chunked as its
+// length is unknown. The transport copies the body with WriteTo directly,
+// without an intermediate buffer, other readers of the body go through a
+// pipe. Like any stream, the body is not replayed on redirects or retries.
+func (r *Request) WithWriterTo(wt io.WriterTo, contentType string) *Request {
+	r.mime = contentType
+	r.body = &writerToReader{wt: wt}
+	r.getBody = nil
+	return r
+}
+
+// writerToReader adapts an io.WriterTo to an io.Reader, the pipe is only
+// started when the body is read instead of copied with WriteTo.
+type writerToReader struct {
+	wt   io.WriterTo
+	once sync.Once
+	pr   *io.PipeReader
+}
+
+func (w *writerToReader) WriteTo(dst io.Writer) (int64, error) {
+	return w.wt.WriteTo(dst)
+}
+
+func (w *writerToReader) Read(p []byte) (int, error) {
+	w.once.Do(func() {
+		pr, pw := io.Pipe()
+		w.pr = pr
+		go func() {
+			_, err := w.wt.WriteTo(pw)
+			pw.CloseWithError(err)
+		}()
+	})
+	return w.pr.Read(p)
+}
 
-	for field, values := range files {
+// Close stops a started pipe.
+func (w *writerToReader) Close() error {
+	w.once.Do(func() {})
+	if w.pr != nil {
+		w.pr.Close()
+	}
+	return nil
+}
+
+// AttachFile sends the file as the "file" part of a multipart form.
+// The file is streamed, not buffered, and replayed on redirects and
+// retries by seeking back, it is closed once the request is done.
+func (r *Request) AttachFile(reader io.Reader, contentType ...string) *Request {
+	if _, ok := reader.(*os.File); !ok {
+		return r
+	}
+
+	part := newFormPart("file", reader, "")
+	if len(contentType) > 0 {
+		part.contentType = contentType[0]
+	}
+	return r.withMultipart([]formPart{part})
+}
+
+// AttachFiles sends a multipart form of files and fields, the values are
+// the reader and an optional content type. Like with AttachFile the parts
+// are streamed and closed once the request is done. Invalid values fail
+// the request with the errors of all the fields, nothing is sent.
+//
+// Deprecated: use WithMultipart, it allows repeated fields and checks the
+// types at compile time.
+func (r *Request) AttachFiles(files map[string][]interface{}) *Request {
+	fields := make([]string, 0, len(files))
+	for field := range files {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	form := NewMultipartForm()
+	var errs []error
+	for _, field := range fields {
+		values := files[field]
 		if len(values) == 0 {
-			r.err = ErrorEmptyListValues
-			return r
+			errs = append(errs, fmt.Errorf("%s: %w", field, ErrorEmptyListValues))
+			continue
 		}
 		reader, ok := values[0].(io.Reader)
 		if !ok {
-			r.err = errors.New("value is not an interface io.Reader")
+			errs = append(errs, fmt.Errorf("%s: value %T is not an io.Reader", field, values[0]))
 			continue
 		}
 
+		var contentType string
 		if len(values) > 1 {
 			contentType, ok = values[1].(string)
 			if !ok {
-				r.err = errors.New("value is not a string")
+				errs = append(errs, fmt.Errorf("%s: content type %T is not a string", field, values[1]))
 				continue
 			}
 		}
 
-		if f, ok := reader.(*os.File); ok {
-			fileName = filepath.Base(f.Name())
-			closeReaders = append(closeReaders, f)
-
-			if part, err = CreateFormFile(
-				writer, field, fileName, contentType); err != nil {
-				r.err = err
-				continue
-			}
+		if _, ok := reader.(*os.File); ok {
+			form.AddFile(field, "", reader, contentType)
 		} else {
-			if part, err = writer.CreateFormField(field); err != nil {
-				r.err = err
-				continue
-			}
-		}
-		if _, err = io.Copy(part, reader); err != nil {
-			r.err = err
-			continue
+			form.parts = append(form.parts, newFormPart(field, reader, contentType))
 		}
 	}
-
-	r.mime = writer.FormDataContentType()
-	writer.Close()
-	r.body = body
-
-	for _, reader := range closeReaders {
-		closeReader(reader)
+	if len(errs) > 0 {
+		for _, part := range form.parts {
+			closeReader(part.reader)
+		}
+		r.err = errors.Join(errs...)
+		return r
 	}
 
-	return r
+	return r.WithMultipart(form)
 }
@@ -0,0 +1,762 @@
+package www
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+
+	var hits int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+	}))
+	defer srv.Close()
+
+	t.Run("BODY ON GET", func(t *testing.T) {
+		resp := NewRequest(NewClient().WithStrict(true)).
+			JSON(map[string]string{"key": "value"}).
+			Get(srv.URL)
+
+		if !errors.Is(resp.Error(), ErrorBodyNotAllowed) {
+			t.Errorf("Error:got %v, want %v", resp.Error(), ErrorBodyNotAllowed)
+		}
+		if hits != 0 {
+			t.Errorf("the invalid request was sent")
+		}
+	})
+
+	t.Run("EMPTY URL", func(t *testing.T) {
+		resp := NewRequest(NewClient().WithStrict(true)).Get("")
+
+		if !errors.Is(resp.Error(), ErrorEmptyURL) {
+			t.Errorf("Error:got %v, want %v", resp.Error(), ErrorEmptyURL)
+		}
+	})
+
+	t.Run("CONFLICTING CONTENT TYPE", func(t *testing.T) {
+		resp := NewRequest(NewClient().WithStrict(true)).
+			JSON(map[string]string{"key": "value"}).
+			Post(srv.URL, http.Header{"Content-Type": {"text/plain"}})
+
+		if !errors.Is(resp.Error(), ErrorConflictingContentType) {
+			t.Errorf("Error:got %v, want %v", resp.Error(), ErrorConflictingContentType)
+		}
+	})
+
+	t.Run("LENIENT", func(t *testing.T) {
+		r := NewRequest(NewClient()).JSON(map[string]string{"key": "value"})
+		resp := r.Get(srv.URL)
+
+		if resp.Error() != nil {
+			t.Errorf("%v", resp.Error())
+		}
+		if hits != 1 {
+			t.Errorf("hits:got %d, want 1", hits)
+		}
+		if !errors.Is(r.Validate(), ErrorBodyNotAllowed) {
+			t.Errorf("Validate:got %v, want %v", r.Validate(), ErrorBodyNotAllowed)
+		}
+	})
+}
+
+func TestWithSeeker(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/upload" {
+			http.Redirect(w, r, "/final", http.StatusTemporaryRedirect)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		fmt.Fprintf(w, "%d:%s", r.ContentLength, body)
+	}))
+	defer srv.Close()
+
+	t.Run("REDIRECT", func(t *testing.T) {
+		f, err := os.CreateTemp(t.TempDir(), "upload")
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.WriteString("hello world")
+		f.Seek(0, io.SeekStart)
+
+		resp := NewRequest(NewClient()).
+			WithSeeker(f, "text/plain").
+			Post(srv.URL + "/upload")
+		if resp.Error() != nil {
+			t.Fatalf("%v", resp.Error())
+		}
+		if got := resp.Text(); got != "11:hello world" {
+			t.Errorf("got %q, want %q", got, "11:hello world")
+		}
+	})
+
+	t.Run("NOT SEEKABLE", func(t *testing.T) {
+		pr, pw, err := os.Pipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer pw.Close()
+
+		resp := NewRequest(NewClient()).
+			WithSeeker(pr, "text/plain").
+			Post(srv.URL + "/upload")
+		if !errors.Is(resp.Error(), ErrorNotSeekable) {
+			t.Errorf("Error:got %v, want %v", resp.Error(), ErrorNotSeekable)
+		}
+	})
+}
+
+func TestWithFileRedirect(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/upload" {
+			http.Redirect(w, r, "/final", http.StatusTemporaryRedirect)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		fmt.Fprintf(w, "%s:%s", r.Header.Get("Content-Type"), body)
+	}))
+	defer srv.Close()
+
+	f, err := os.CreateTemp(t.TempDir(), "upload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("hello world")
+	f.Seek(0, io.SeekStart)
+
+	resp := NewRequest(NewClient()).WithFile(f).Post(srv.URL + "/upload")
+	if resp.Error() != nil {
+		t.Fatalf("%v", resp.Error())
+	}
+	if got, want := resp.Text(), "binary/octet-stream:hello world"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if _, err := f.Stat(); err == nil {
+		t.Errorf("the file is not closed")
+	}
+}
+
+func TestContentType(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("Content-Type")))
+	}))
+	defer srv.Close()
+
+	const vendor = "application/vnd.api+json"
+
+	resp := NewRequest(NewClient()).
+		ContentType(vendor).
+		JSON(map[string]string{"key": "value"}).
+		Post(srv.URL)
+	if got := resp.Text(); got != vendor {
+		t.Errorf("got %q, want %q", got, vendor)
+	}
+}
+
+func TestMaxUploadSize(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+	}))
+	defer srv.Close()
+
+	t.Run("STREAM", func(t *testing.T) {
+		body := io.LimitReader(neverEnding('x'), 1<<20)
+		r := NewRequest(NewClient()).MaxUploadSize(1024).WithFile(body)
+		resp := r.Post(srv.URL)
+		if !errors.Is(resp.Error(), ErrUploadTooLarge) {
+			t.Errorf("Error:got %v, want %v", resp.Error(), ErrUploadTooLarge)
+		}
+		if !errors.Is(r.Error(), ErrUploadTooLarge) {
+			t.Errorf("Request.Error:got %v, want %v", r.Error(), ErrUploadTooLarge)
+		}
+	})
+
+	t.Run("KNOWN LENGTH", func(t *testing.T) {
+		resp := NewRequest(NewClient()).MaxUploadSize(4).
+			WithForm(&url.Values{"key": {"value"}}).
+			Post(srv.URL)
+		if !errors.Is(resp.Error(), ErrUploadTooLarge) {
+			t.Errorf("Error:got %v, want %v", resp.Error(), ErrUploadTooLarge)
+		}
+	})
+
+	t.Run("UNDER LIMIT", func(t *testing.T) {
+		resp := NewRequest(NewClient()).MaxUploadSize(1024).
+			WithFile(strings.NewReader("hello")).
+			Post(srv.URL)
+		if resp.Error() != nil {
+			t.Errorf("%v", resp.Error())
+		}
+	})
+}
+
+type neverEnding byte
+
+func (b neverEnding) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = byte(b)
+	}
+	return len(p), nil
+}
+
+func TestNoBody(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		fmt.Fprintf(w, "%s|%s|%s", r.Header.Get("Content-Length"), r.Header.Get("Content-Type"), body)
+	}))
+	defer srv.Close()
+
+	r := NewRequest(NewClient()).JSON(map[string]string{"key": "value"}).NoBody()
+	resp := r.Post(srv.URL)
+	if got := resp.Text(); got != "0||" {
+		t.Errorf("got %q, want %q", got, "0||")
+	}
+
+	body, err := r.Request.GetBody()
+	if err != nil || body != http.NoBody {
+		t.Errorf("GetBody:got %v, %v", body, err)
+	}
+}
+
+func TestWithChannel(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		fmt.Fprintf(w, "%v|%s", r.TransferEncoding, body)
+	}))
+	defer srv.Close()
+
+	ch := make(chan []byte)
+	go func() {
+		for _, chunk := range []string{"hello", " ", "world"} {
+			ch <- []byte(chunk)
+		}
+		close(ch)
+	}()
+
+	resp := NewRequest(NewClient()).WithChannel(ch, "text/plain").Post(srv.URL)
+	if resp.Error() != nil {
+		t.Fatalf("%v", resp.Error())
+	}
+	if got := resp.Text(); got != "[chunked]|hello world" {
+		t.Errorf("got %q, want %q", got, "[chunked]|hello world")
+	}
+}
+
+func TestFormMap(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		fmt.Fprintf(w, "%s|%s", r.Header.Get("Content-Type"), body)
+	}))
+	defer srv.Close()
+
+	form := map[string]string{"a": "1", "b": "", "c": "x y"}
+
+	got := NewRequest(NewClient()).FormMap(form).Post(srv.URL).Text()
+	if want := "application/x-www-form-urlencoded|a=1&b=&c=x+y"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	got = NewRequest(NewClient()).FormMap(form, true).Post(srv.URL).Text()
+	if want := "application/x-www-form-urlencoded|a=1&c=x+y"; got != want {
+		t.Errorf("omitempty:got %q, want %q", got, want)
+	}
+}
+
+func TestFormMulti(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Write(body)
+	}))
+	defer srv.Close()
+
+	got := NewRequest(NewClient()).
+		FormMulti(map[string][]string{"tag": {"go", "http"}, "q": {"client"}}).
+		Post(srv.URL).Text()
+	if want := "q=client&tag=go&tag=http"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestRequestClose(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%v %s", r.Close, r.Header.Get("Connection"))
+	}))
+	defer srv.Close()
+
+	if got := NewRequest(NewClient()).Close().Get(srv.URL).Text(); got != "true close" {
+		t.Errorf("got %q, want %q", got, "true close")
+	}
+	if got := NewRequest(NewClient()).Get(srv.URL).Text(); got != "false " {
+		t.Errorf("got %q, want %q", got, "false ")
+	}
+}
+
+func TestPercentEncodeSpaces(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.RawQuery))
+	}))
+	defer srv.Close()
+
+	params := &url.Values{"q": {"go http+client"}}
+
+	if got := NewRequest(NewClient()).WithQuery(params).Get(srv.URL).Text(); got != "q=go+http%2Bclient" {
+		t.Errorf("form-style:got %q", got)
+	}
+	got := NewRequest(NewClient()).WithQuery(params).PercentEncodeSpaces().Get(srv.URL).Text()
+	if got != "q=go%20http%2Bclient" {
+		t.Errorf("RFC 3986:got %q", got)
+	}
+}
+
+func TestRetryOnErrorMatch(t *testing.T) {
+
+	r := NewRequest(NewClient()).RetryOnErrorMatch("connection reset by peer", "EOF")
+
+	for err, want := range map[error]bool{
+		nil: false,
+		&url.Error{Op: "Get", URL: "http://x", Err: io.ErrUnexpectedEOF}:    true,
+		errors.New("read tcp 127.0.0.1:80: read: connection reset by peer"): true,
+		errors.New("dial tcp 127.0.0.1:80: connect: connection refused"):    false,
+	} {
+		if got := r.errorMatches(err); got != want {
+			t.Errorf("%v:got %v, want %v", err, got, want)
+		}
+	}
+}
+
+func TestSignQuery(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.RawQuery))
+	}))
+	defer srv.Close()
+
+	sign := func(params url.Values) url.Values {
+		params.Set("sig", fmt.Sprintf("%x", len(params.Encode())))
+		return params
+	}
+
+	got := NewRequest(NewClient()).
+		WithQuery(&url.Values{"b": {"2"}, "a": {"1"}}).
+		SignQuery(sign).
+		Get(srv.URL).Text()
+	if want := "a=1&b=2&sig=7"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestOn1xx(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Link", "</style.css>; rel=preload; as=style")
+		w.WriteHeader(http.StatusEarlyHints)
+		w.Header().Del("Link")
+		w.Write([]byte("final"))
+	}))
+	defer srv.Close()
+
+	var got []string
+	resp := NewRequest(NewClient()).On1xx(func(code int, header http.Header) {
+		got = append(got, fmt.Sprintf("%d %s", code, header.Get("Link")))
+	}).Get(srv.URL)
+
+	if resp.Text() != "final" {
+		t.Errorf("Text:got %q, want %q", resp.Text(), "final")
+	}
+	if want := "103 </style.css>; rel=preload; as=style"; len(got) != 1 || got[0] != want {
+		t.Errorf("got %q, want [%q]", got, want)
+	}
+}
+
+func TestOnDNS(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	uri := strings.Replace(srv.URL, "127.0.0.1", "localhost", 1)
+
+	var hosts []string
+	var addrs []net.IPAddr
+	resp := NewRequest(NewClient()).OnDNS(func(host string, resolved []net.IPAddr, err error) {
+		if err != nil {
+			t.Errorf("lookup: %v", err)
+		}
+		hosts = append(hosts, host)
+		addrs = resolved
+	}).Get(uri)
+	if resp.Error() != nil {
+		t.Fatalf("%v", resp.Error())
+	}
+
+	if len(hosts) != 1 || hosts[0] != "localhost" {
+		t.Errorf("host:got %q, want [localhost]", hosts)
+	}
+	loopback := false
+	for _, addr := range addrs {
+		loopback = loopback || addr.IP.IsLoopback()
+	}
+	if !loopback {
+		t.Errorf("addrs:got %v, want a loopback address", addrs)
+	}
+
+	hosts = nil
+	NewRequest(NewClient()).OnDNS(func(host string, _ []net.IPAddr, _ error) {
+		hosts = append(hosts, host)
+	}).Get(srv.URL)
+	if len(hosts) != 0 {
+		t.Errorf("IP literal:got %q, want no lookup", hosts)
+	}
+}
+
+func TestQuery(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.RawQuery))
+	}))
+	defer srv.Close()
+
+	type filter struct {
+		Tags   []string  `url:"tag"`
+		Since  time.Time `url:"since,unix"`
+		Page   int       `url:"page,omitempty"`
+		Limit  int       `url:"limit"`
+		Secret string    `url:"-"`
+	}
+
+	got := NewRequest(NewClient()).
+		Query(
+			url.Values{"q": {"go"}, "limit": {"10"}},
+			filter{Tags: []string{"a", "b"}, Since: time.Unix(1630499400, 0), Limit: 50, Secret: "x"},
+			map[string]string{"q": "http"},
+		).
+		Get(srv.URL).Text()
+	if want := "limit=50&q=http&since=1630499400&tag=a&tag=b"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	resp := NewRequest(NewClient()).Query(42).Get(srv.URL)
+	if !errors.Is(resp.Error(), ErrorUnsupportedValues) {
+		t.Errorf("Error:got %v, want %v", resp.Error(), ErrorUnsupportedValues)
+	}
+}
+
+func TestStructParams(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		fmt.Fprintf(w, "%s|%s|%s", r.URL.RawQuery, r.Header.Get("Content-Type"), body)
+	}))
+	defer srv.Close()
+
+	type params struct {
+		IDs   []int     `url:"id" form:"id"`
+		Since time.Time `url:"since,unix" form:"from"`
+		Name  string    `url:"name,omitempty" form:"name,omitempty"`
+	}
+	v := &params{IDs: []int{1, 2}, Since: time.Unix(1630499400, 0).UTC()}
+
+	got := NewRequest(NewClient()).WithQueryStruct(v).WithFormStruct(v).Post(srv.URL).Text()
+	want := "id=1&id=2&since=1630499400|application/x-www-form-urlencoded|from=2021-09-01T12%3A30%3A00Z&id=1&id=2"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	for name, req := range map[string]*Request{
+		"query": NewRequest(NewClient()).WithQueryStruct(url.Values{}),
+		"form":  NewRequest(NewClient()).WithFormStruct("a=b"),
+	} {
+		if resp := req.Get(srv.URL); !errors.Is(resp.Error(), ErrorUnsupportedValues) {
+			t.Errorf("%s:got %v, want %v", name, resp.Error(), ErrorUnsupportedValues)
+		}
+	}
+}
+
+type closeFlag struct {
+	io.Reader
+	closed bool
+}
+
+func (c *closeFlag) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestWithContext(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fast" {
+			return
+		}
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer srv.Close()
+
+	t.Run("CANCELLED", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		body := &closeFlag{Reader: strings.NewReader("data")}
+		resp := NewRequest(NewClient()).WithContext(ctx).WithFile(body).Post(srv.URL)
+		if !errors.Is(resp.Error(), context.Canceled) {
+			t.Errorf("Error:got %v, want %v", resp.Error(), context.Canceled)
+		}
+		if !body.closed {
+			t.Errorf("the body is not closed")
+		}
+	})
+
+	t.Run("DEADLINE", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		resp := NewRequest(NewClient()).WithContext(ctx).Get(srv.URL)
+		if !errors.Is(resp.Error(), context.DeadlineExceeded) {
+			t.Errorf("Error:got %v, want %v", resp.Error(), context.DeadlineExceeded)
+		}
+	})
+
+	t.Run("TIMEOUT", func(t *testing.T) {
+		resp := NewRequest(NewClient()).WithTimeout(50 * time.Millisecond).Get(srv.URL)
+		if !errors.Is(resp.Error(), context.DeadlineExceeded) {
+			t.Errorf("Error:got %v, want %v", resp.Error(), context.DeadlineExceeded)
+		}
+
+		resp = NewRequest(NewClient()).WithTimeout(500 * time.Millisecond).Get(srv.URL + "/fast")
+		if resp.Error() != nil || resp.StatusCode != http.StatusOK {
+			t.Errorf("in time:got %v", resp.Error())
+		}
+	})
+
+	t.Run("VALUES", func(t *testing.T) {
+		type key string
+		var got []interface{}
+		inner := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			got = append(got, req.Context().Value(key("user")), req.Context().Value(key("start")))
+			return http.DefaultTransport.RoundTrip(req)
+		})
+		outer := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			ctx := context.WithValue(req.Context(), key("start"), "outer")
+			return inner.RoundTrip(req.WithContext(ctx))
+		})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		resp := NewRequest(NewClient().WithTransport(outer)).
+			WithValue(key("user"), "alice").
+			WithContext(ctx).
+			Get(srv.URL)
+		if fmt.Sprint(got) != "[alice outer]" {
+			t.Errorf("values:got %v, want [alice outer]", got)
+		}
+		if !errors.Is(resp.Error(), context.DeadlineExceeded) {
+			t.Errorf("Error:got %v, want %v", resp.Error(), context.DeadlineExceeded)
+		}
+	})
+}
+
+// chunks writes size bytes in 32 KiB chunks.
+type chunks struct {
+	size  int
+	chunk []byte
+}
+
+func (c *chunks) WriteTo(w io.Writer) (int64, error) {
+	var n int64
+	for n < int64(c.size) {
+		m, err := w.Write(c.chunk)
+		n += int64(m)
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+func TestWithWriterTo(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, _ := io.Copy(io.Discard, r.Body)
+		fmt.Fprintf(w, "%v|%s|%d", r.TransferEncoding, r.Header.Get("Content-Type"), n)
+	}))
+	defer srv.Close()
+
+	body := &chunks{size: 1 << 20, chunk: make([]byte, 32<<10)}
+	got := NewRequest(NewClient()).WithWriterTo(body, "application/octet-stream").Post(srv.URL).Text()
+	if want := "[chunked]|application/octet-stream|1048576"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// read through the pipe
+	data, err := io.ReadAll(&writerToReader{wt: &chunks{size: 100, chunk: []byte("0123456789")}})
+	if err != nil || len(data) != 100 {
+		t.Errorf("Read:got %d bytes, %v", len(data), err)
+	}
+}
+
+func BenchmarkWithWriterTo(b *testing.B) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+	}))
+	defer srv.Close()
+
+	cl := NewClient()
+	newBody := func() *chunks { return &chunks{size: 8 << 20, chunk: make([]byte, 32<<10)} }
+
+	b.Run("WriterTo", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			NewRequest(cl).WithWriterTo(newBody(), "application/octet-stream").Post(srv.URL).Content()
+		}
+	})
+
+	b.Run("Buffered", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var buf bytes.Buffer
+			newBody().WriteTo(&buf)
+			NewRequest(cl).WithFile(&buf).Post(srv.URL).Content()
+		}
+	})
+}
+
+func TestJSONTemplate(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		fmt.Fprintf(w, "%s|%s", r.Header.Get("Content-Type"), body)
+	}))
+	defer srv.Close()
+
+	data := map[string]interface{}{"Name": `say "hi"`, "Count": 3}
+
+	got := NewRequest(NewClient()).
+		JSONTemplate(`{"name": {{json .Name}}, "count": {{.Count}}, "kind": "greeting"}`, data).
+		Post(srv.URL).Text()
+	if want := `application/json|{"name": "say \"hi\"", "count": 3, "kind": "greeting"}`; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	r := NewRequest(NewClient()).JSONTemplate(`{"name": "{{.Name}}"}`, data)
+	if !errors.Is(r.Error(), ErrorInvalidJSON) {
+		t.Errorf("invalid JSON:got %v, want %v", r.Error(), ErrorInvalidJSON)
+	}
+	if r := NewRequest(NewClient()).JSONTemplate(`{{.Missing.Field}}`, data); r.Error() == nil {
+		t.Errorf("execution:got nil error")
+	}
+}
+
+func TestAuthHelpers(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, _ := r.BasicAuth()
+		cookie, _ := r.Cookie("session")
+		fmt.Fprintf(w, "%s|%s:%s|%s|%v", r.Header.Get("Authorization"), user, password, r.Header.Get("X-Trace"), cookie)
+	}))
+	defer srv.Close()
+
+	got := NewRequest(NewClient()).WithBasicAuth("user", "pa:ss").WithHeader("X-Trace", "1").Get(srv.URL).Text()
+	if want := "Basic dXNlcjpwYTpzcw==|user:pa:ss|1|"; got != want {
+		t.Errorf("basic:got %q, want %q", got, want)
+	}
+
+	got = NewRequest(NewClient()).
+		WithBearerToken("token").
+		SetCookies(&http.Cookie{Name: "session", Value: "abc"}).
+		Get(srv.URL).Text()
+	if want := "Bearer token|:||session=abc"; got != want {
+		t.Errorf("bearer:got %q, want %q", got, want)
+	}
+
+	got = NewRequest(NewClient()).WithBearerToken("token").Get(srv.URL, http.Header{"Authorization": {"Bearer explicit"}}).Text()
+	if !strings.HasPrefix(got, "Bearer explicit|") {
+		t.Errorf("explicit:got %q", got)
+	}
+}
+
+func TestAccept(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("Accept")))
+	}))
+	defer srv.Close()
+
+	cl := NewClient().WithAccept("application/xml")
+	cl.DefaultHeaders = http.Header{"Accept": {"text/html"}}
+	jsonCl := NewClient().WithAccept("application/xml").WithJSONAccept()
+
+	tests := []struct {
+		name string
+		req  *Request
+		want string
+	}{
+		{"no default", NewRequest(NewClient()), ""},
+		{"default", NewRequest(NewClient().WithAccept()), "application/json"},
+		{"client", NewRequest(cl), "application/xml"},
+		{"request", NewRequest(cl).Accept("application/json", "text/plain"),
+			"application/json;q=1.0, text/plain;q=0.8"},
+		{"WithHeader", NewRequest(cl).Accept("text/plain").WithHeader("Accept", "image/png"), "image/png"},
+		{"json body", NewRequest(jsonCl).Json(1), "application/json"},
+		{"json body off", NewRequest(cl).Json(1), "application/xml"},
+		{"form body", NewRequest(jsonCl).WithForm(&url.Values{"a": {"1"}}), "application/xml"},
+		{"json body and request", NewRequest(jsonCl).Json(1).Accept("text/plain"), "text/plain"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.req.Post(srv.URL).Text(); got != tt.want {
+				t.Errorf("Accept:got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	got := NewRequest(cl).Post(srv.URL, http.Header{"Accept": {"*/*"}}).Text()
+	if got != "*/*" {
+		t.Errorf("Do headers:got %q, want %q", got, "*/*")
+	}
+}
+
+func TestPathParams(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.EscapedPath()))
+	}))
+	defer srv.Close()
+
+	cl := NewClient().WithBaseURL(srv.URL + "/api/")
+	got := NewRequest(cl).
+		WithPathParams(map[string]string{"id": "42"}).
+		WithPathParams(map[string]string{"name": "a/b c"}).
+		Get("users/{id}/files/{name}").
+		Text()
+	if want := "/api/users/42/files/a%2Fb%20c"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	got = NewRequest(cl).WithPathParams(map[string]string{"id": "42"}).Get("users/{id}/{missing}").Text()
+	if want := "/api/users/42/%7Bmissing%7D"; got != want {
+		t.Errorf("missing:got %q, want %q", got, want)
+	}
+}
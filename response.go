@@ -1,26 +1,112 @@
 package www
 
 import (
+	"bufio"
+	"compress/flate"
 	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"io/ioutil"
+	"mime"
 	"net/http"
+	"strconv"
 	"strings"
+	"sync/atomic"
 
+	"github.com/andybalholm/brotli"
 	"github.com/softlandia/cpd"
 )
 
+var (
+	ErrorJSONPath   = errors.New("no value at the JSON path")
+	ErrorNoResponse = errors.New("no response")
+)
+
+var (
+	ErrorUnexpectedStatus      = errors.New("unexpected status")
+	ErrorUnexpectedContentType = errors.New("unexpected content type")
+)
+
+var ErrorInvalidBase64 = errors.New("the body is not valid base64")
+
+var ErrContentLengthMismatch = errors.New("the body is shorter than its Content-Length")
+
 type Response struct {
 	*http.Response
-	err     error
-	content []byte
+	err       error
+	content   []byte
+	fromCache bool
+	cacheKey  string
+	request   *Request
+	// the body was decompressed by the client
+	decompressed    bool
+	contentEncoding string
+	// the body was converted to UTF-8 from the declared charset
+	transcoded bool
+	// the server answered 304 Not Modified
+	notModified bool
+	counter     *countingBody
+}
+
+// EncodingInfo describes the compression negotiated for an exchange.
+type EncodingInfo struct {
+	AcceptEncoding  string // Accept-Encoding sent with the request
+	RequestEncoding string // Content-Encoding of the request body
+	ContentEncoding string // Content-Encoding of the response body
+	// net/http requested gzip itself and decompressed the body
+	TransportDecompressed bool
+	// the client decompressed the body (see WithRawBodies)
+	ClientDecompressed bool
+}
+
+// Encoding reports the compression used for the request and the response.
+// It is safe to call on a failed response.
+func (resp *Response) Encoding() (info EncodingInfo) {
+	if resp == nil || resp.Response == nil {
+		return info
+	}
+
+	if resp.Request != nil {
+		info.AcceptEncoding = resp.Request.Header.Get("Accept-Encoding")
+		info.RequestEncoding = resp.Request.Header.Get("Content-Encoding")
+	}
+	info.ContentEncoding = resp.Header.Get("Content-Encoding")
+	if resp.contentEncoding != "" {
+		info.ContentEncoding = resp.contentEncoding
+	}
+	if resp.Uncompressed {
+		// the transport removes the headers it handled
+		info.TransportDecompressed = true
+		info.AcceptEncoding = "gzip"
+		info.ContentEncoding = "gzip"
+	}
+	info.ClientDecompressed = resp.decompressed
+
+	return info
 }
 
 func (resp Response) Error() error {
 	return resp.err
 }
 
+// FromCache reports whether the response was served by the response cache
+// without a network round trip. A cached body returned after a successful
+// revalidation (the server answered 304 Not Modified) is reported as cached
+// as well, since the body did not travel over the network.
+func (resp Response) FromCache() bool {
+	return resp.fromCache
+}
+
+// CacheKey returns the key the response is stored under in the response
+// cache, it is empty for responses that did not go through the cache.
+func (resp Response) CacheKey() string {
+	return resp.cacheKey
+}
+
 func (resp *Response) Content() []byte {
 	if resp.content == nil {
 		resp.content = resp.readAll()
@@ -37,6 +123,38 @@ func (resp *Response) Text() string {
 	return string(resp.content)
 }
 
+// EnsureStatus sets the response error when the status code is not one of
+// codes, e.g. resp := req.Get(uri).EnsureStatus(200, 204).
+func (resp *Response) EnsureStatus(codes ...int) *Response {
+	if resp.err != nil || resp.Response == nil {
+		return resp
+	}
+	for _, code := range codes {
+		if resp.StatusCode == code {
+			return resp
+		}
+	}
+	resp.err = resp.statusError()
+	return resp
+}
+
+// EnsureContentType sets the response error when the media type of the
+// response is not one of types, parameters such as charset are ignored.
+// It catches HTML error pages served with 200 OK.
+func (resp *Response) EnsureContentType(types ...string) *Response {
+	if resp.err != nil || resp.Response == nil {
+		return resp
+	}
+	got, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	for _, contentType := range types {
+		if want, _, _ := mime.ParseMediaType(contentType); want == got {
+			return resp
+		}
+	}
+	resp.err = fmt.Errorf("%w: %q, want one of %q", ErrorUnexpectedContentType, got, types)
+	return resp
+}
+
 func (resp Response) ContentType(contentTypes ...string) (mime, charset string) {
 	var contentType string
 
@@ -67,6 +185,16 @@ func (resp Response) Charset(contentTypes ...string) (charset string) {
 	return charset
 }
 
+// RequestHeaders returns the headers actually sent for this response,
+// after defaults, auth and transport wrappers were applied, unlike
+// Request.Headers which reflects the builder. Nil for a failed request.
+func (resp *Response) RequestHeaders() http.Header {
+	if resp == nil || resp.Response == nil || resp.Response.Request == nil {
+		return nil
+	}
+	return resp.Response.Request.Header
+}
+
 func (resp *Response) DetectCodePage() string {
 	if resp.content == nil {
 		resp.content = resp.readAll()
@@ -89,46 +217,211 @@ func (resp Response) Headers() http.Header {
 	return resp.Header
 }
 
-func (resp *Response) Json() (data map[string]interface{}) {
-	contentType := resp.Header.Get("Content-Type")
-	if contentType == "application/json" {
-		if resp.content == nil {
-			resp.content = resp.readAll(true)
+// Bytes reads the body once and returns it, the body is closed and kept
+// for the following calls.
+func (resp *Response) Bytes() ([]byte, error) {
+	if resp.err != nil {
+		return nil, resp.err
+	}
+	if resp.Response == nil {
+		return nil, ErrorNoResponse
+	}
+	if resp.content == nil {
+		resp.content = resp.readAll()
+		if resp.err != nil {
+			return nil, resp.err
 		}
-		if err := json.Unmarshal(resp.content, &data); err != nil {
-			resp.err = err
+	}
+
+	return resp.content, nil
+}
+
+// Json decodes the JSON body into v. The body is read once and kept, so
+// Json, Content, etc. can be called again. It fails with the error of the
+// request, with an *HTTPError for a 4xx or 5xx response and with
+// ErrorUnexpectedContentType when the body is not JSON (application/json
+// or a +json type).
+func (resp *Response) Json(v interface{}) error {
+	data, err := resp.jsonBody()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// jsonBody reads the body once for the JSON decoders after checking the
+// request error, the status and the content type as told by Json. The
+// body is returned with the status and content type errors.
+func (resp *Response) jsonBody() ([]byte, error) {
+	if resp.err != nil {
+		return nil, resp.err
+	}
+	if resp.Response == nil {
+		return nil, ErrorNoResponse
+	}
+	if resp.content == nil {
+		resp.content = resp.readAll(true)
+		if resp.err != nil {
+			return nil, resp.err
+		}
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return resp.content, resp.statusError()
+	}
+	// a 304 answering CachedValidators usually carries no Content-Type,
+	// the cached body is trusted then
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType == "" && resp.fromCache {
+		return resp.content, nil
+	}
+	if mediaType != "application/json" && !strings.HasSuffix(mediaType, "+json") {
+		return resp.content, fmt.Errorf("%w: %q, want JSON", ErrorUnexpectedContentType, mediaType)
+	}
+	return resp.content, nil
+}
+
+func (resp *Response) JSON(v interface{}) error {
+	return resp.Json(v)
+}
+
+// JSONWithRaw decodes the body into v and returns the raw bytes as well,
+// the body is read once and kept for the following Content calls. It
+// fails as Json does, the raw bytes are returned with the status and
+// content type errors too.
+func (resp *Response) JSONWithRaw(v interface{}) ([]byte, error) {
+	data, err := resp.jsonBody()
+	if err != nil {
+		return data, err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return data, err
+	}
+
+	return data, nil
+}
+
+// Base64 returns the base64-decoded body. The standard and the URL-safe
+// alphabets are accepted, with or without padding, whitespace and line
+// breaks are ignored. The raw body is kept for the following Content calls.
+func (resp *Response) Base64() ([]byte, error) {
+	if resp.err != nil {
+		return nil, resp.err
+	}
+	if resp.content == nil {
+		resp.content = resp.readAll()
+		if resp.err != nil {
+			return nil, resp.err
 		}
 	}
 
+	encoded := strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '\t', '\r', '\n', '=':
+			return -1
+		}
+		return r
+	}, string(resp.content))
+
+	encoding := base64.RawStdEncoding
+	if strings.ContainsAny(encoded, "-_") {
+		encoding = base64.RawURLEncoding
+	}
+	data, err := encoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrorInvalidBase64, err)
+	}
+	return data, nil
+}
+
+// JSONInsensitive decodes the JSON body into v with object keys folded to
+// lower case. Struct fields are matched case-insensitively by encoding/json
+// anyway, but map keys are kept as sent, so a map[string]interface{} gets
+// "id" for "ID", "Id" and "id". When keys differ only in case, the last
+// one wins. It fails as Json does.
+func (resp *Response) JSONInsensitive(v interface{}) error {
+	body, err := resp.jsonBody()
+	if err != nil {
+		return err
+	}
+
+	var data interface{}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return err
+	}
+	folded, err := json.Marshal(foldKeys(data))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(folded, v)
+}
+
+func foldKeys(data interface{}) interface{} {
+	switch v := data.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for key, val := range v {
+			out[strings.ToLower(key)] = foldKeys(val)
+		}
+		return out
+	case []interface{}:
+		for i := range v {
+			v[i] = foldKeys(v[i])
+		}
+	}
 	return data
 }
 
-func (resp Response) JSON() (data map[string]interface{}) {
-	return resp.Json()
+// JSONField decodes the value at a dotted path of the JSON body into dest,
+// array elements are addressed by their index, e.g. "data.items.0.id".
+// It fails as Json does.
+func (resp *Response) JSONField(path string, dest interface{}) error {
+	body, err := resp.jsonBody()
+	if err != nil {
+		return err
+	}
+
+	value := json.RawMessage(body)
+	for i, key := range strings.Split(path, ".") {
+		var (
+			object map[string]json.RawMessage
+			array  []json.RawMessage
+			ok     bool
+		)
+		if err := json.Unmarshal(value, &object); err == nil {
+			value, ok = object[key]
+		} else if err := json.Unmarshal(value, &array); err == nil {
+			if n, err := strconv.Atoi(key); err == nil && n >= 0 && n < len(array) {
+				value, ok = array[n], true
+			}
+		}
+		if !ok {
+			return fmt.Errorf("%w: %q has no %q",
+				ErrorJSONPath, strings.Join(strings.Split(path, ".")[:i], "."), key)
+		}
+	}
+
+	if err := json.Unmarshal(value, dest); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
 }
 
 func (resp *Response) readAll(convertToUTF8 ...bool) (content []byte) {
-	var (
-		reader io.Reader
-		err    error
-	)
-	contentEncoding := resp.Header.Get("Content-Encoding")
-
-	switch contentEncoding {
-	case "gzip":
-		reader, err = gzip.NewReader(resp.Body)
+	defer resp.Body.Close()
+
+	reader, err := resp.bodyReader()
+	if err != nil {
+		resp.err = err
+		return nil
+	}
+
+	if len(convertToUTF8) > 0 && convertToUTF8[0] && !resp.transcoded {
+		reader, err = cpd.NewReader(reader)
 		if err != nil {
 			resp.err = err
 			return nil
 		}
-	default:
-		reader = resp.Body
-	}
-
-	defer resp.Body.Close()
-
-	if len(convertToUTF8) > 0 && convertToUTF8[0] {
-		reader = resp.NewReader()
 	}
 
 	content, err = ioutil.ReadAll(reader)
@@ -138,3 +431,221 @@ func (resp *Response) readAll(convertToUTF8 ...bool) (content []byte) {
 
 	return content
 }
+
+// bodyReader returns the body limited to the maximum response size of
+// the client, it was decompressed by send already.
+func (resp *Response) bodyReader() (io.Reader, error) {
+	var reader io.Reader = resp.Body
+
+	// the limit applies to the decompressed stream
+	if resp.request != nil && resp.request.client.maxResponseSize > 0 {
+		reader = &maxBytesReader{reader, resp.request.client.maxResponseSize, ErrBodyTooLarge}
+	}
+
+	if resp.request != nil && resp.request.client.autoTranscode {
+		reader, resp.transcoded = transcode(resp.Header, reader)
+	}
+
+	return reader, nil
+}
+
+// Lines returns a scanner over the lines of the (decompressed) body for
+// line-oriented responses such as logs or CSV, lines may be up to 1 MiB.
+// The caller must drain the scanner and close the body.
+func (resp *Response) Lines() (*bufio.Scanner, error) {
+	if resp.err != nil {
+		return nil, resp.err
+	}
+	if resp.Response == nil {
+		return nil, ErrorNoResponse
+	}
+
+	reader, err := resp.bodyReader()
+	if err != nil {
+		return nil, err
+	}
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	return scanner, nil
+}
+
+// PipeTo streams the (decompressed) body of the response as the body of
+// req without buffering it, e.g. to download from one server and upload
+// to another. The Content-Type of the response is forwarded unless req
+// sets its own. The response must not be otherwise consumed; its body is
+// closed once req is sent, an error of the response is reported by req.
+func (resp *Response) PipeTo(req *Request) *Request {
+	if resp.err != nil {
+		req.err = resp.err
+		return req
+	}
+	if resp.Response == nil {
+		req.err = ErrorNoResponse
+		return req
+	}
+
+	reader, err := resp.bodyReader()
+	if err != nil {
+		resp.Body.Close()
+		req.err = err
+		return req
+	}
+
+	if req.mime == "" && req.contentType == "" {
+		req.mime = resp.Header.Get("Content-Type")
+	}
+	req.body = struct {
+		io.Reader
+		io.Closer
+	}{reader, resp.Body}
+	req.getBody = nil
+	return req
+}
+
+// BytesRead reports how many body bytes were read so far, it is exact
+// once the body is closed, with or without a Content-Length. The bytes
+// are counted as received, that is before decompression by Content, Text,
+// etc. It is zero before the first read and for a failed response.
+func (resp *Response) BytesRead() int64 {
+	if resp == nil || resp.counter == nil {
+		return 0
+	}
+	return atomic.LoadInt64(&resp.counter.n)
+}
+
+// countBody makes BytesRead count the reads of the body. The body of
+// a 101 is left as is, it is the io.ReadWriteCloser of the connection.
+func (resp *Response) countBody() {
+	if resp.Response == nil || resp.Body == nil || resp.StatusCode == http.StatusSwitchingProtocols {
+		return
+	}
+	resp.counter = &countingBody{ReadCloser: resp.Body}
+	resp.Body = resp.counter
+}
+
+type countingBody struct {
+	io.ReadCloser
+	n int64
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	atomic.AddInt64(&b.n, int64(n))
+	return n, err
+}
+
+// decodeBody checks the length of the received body (see
+// WithStrictContentLength) and decompresses a gzip, deflate or br body, so
+// every reader of the body sees the plain bytes. Content-Encoding and
+// Content-Length are removed then, the encoding is kept for Encoding.
+func (resp *Response) decodeBody() {
+	cl := resp.client()
+	if resp.Response == nil || resp.Body == nil || cl == nil ||
+		resp.StatusCode == http.StatusSwitchingProtocols {
+		return
+	}
+
+	if cl.strictLength && resp.ContentLength >= 0 && !resp.Uncompressed && len(cl.bodyTransforms) == 0 {
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{&lengthReader{r: resp.Body, want: resp.ContentLength}, resp.Body}
+	}
+
+	if cl.rawBodies {
+		return
+	}
+	encoding := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding")))
+	switch encoding {
+	case "gzip", "x-gzip", "deflate", "br":
+	default: // unknown or none, passed through
+		return
+	}
+	resp.Body = &decompressReader{body: resp.Body, encoding: encoding}
+	resp.contentEncoding = encoding
+	resp.decompressed = true
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+}
+
+// decompressReader starts the decompressor on the first read, an empty
+// body (HEAD, 204, ...) is read as empty. Closing it closes the body.
+type decompressReader struct {
+	body     io.ReadCloser
+	encoding string
+	zr       io.ReadCloser
+	err      error
+}
+
+func (d *decompressReader) Read(p []byte) (int, error) {
+	if d.zr == nil && d.err == nil {
+		switch d.encoding {
+		case "deflate":
+			var zr io.ReadCloser
+			if zr, d.err = newDeflateReader(d.body); d.err == nil {
+				d.zr = zr
+			}
+		case "br": // not decoded by net/http
+			d.zr = ioutil.NopCloser(brotli.NewReader(d.body))
+		default:
+			var zr *gzip.Reader
+			if zr, d.err = gzip.NewReader(d.body); d.err == nil {
+				d.zr = zr
+			}
+		}
+	}
+	if d.err != nil {
+		return 0, d.err
+	}
+	return d.zr.Read(p)
+}
+
+// newDeflateReader reads the zlib format HTTP defines for deflate, or
+// raw DEFLATE data when the body does not start with a zlib header as
+// sent by some servers (older IIS).
+func newDeflateReader(body io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(body)
+	if header, err := br.Peek(2); err == nil && !isZlibHeader(header) {
+		return flate.NewReader(br), nil
+	}
+	return zlib.NewReader(br)
+}
+
+// isZlibHeader checks the compression method (8, deflate) and the check
+// bits of the zlib header, RFC 1950.
+func isZlibHeader(b []byte) bool {
+	return b[0]&0x0f == 8 && (uint16(b[0])<<8|uint16(b[1]))%31 == 0
+}
+
+func (d *decompressReader) Close() error {
+	if d.zr != nil {
+		d.zr.Close()
+	}
+	return d.body.Close()
+}
+
+func (resp *Response) client() *StandardClient {
+	if resp.request == nil {
+		return nil
+	}
+	return resp.request.client
+}
+
+// lengthReader fails with ErrContentLengthMismatch when the body ends
+// before the declared Content-Length.
+type lengthReader struct {
+	r    io.Reader
+	want int64
+	got  int64
+}
+
+func (l *lengthReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	l.got += int64(n)
+	if (err == io.EOF || errors.Is(err, io.ErrUnexpectedEOF)) && l.got != l.want {
+		err = fmt.Errorf("%w: got %d bytes, want %d", ErrContentLengthMismatch, l.got, l.want)
+	}
+	return n, err
+}
@@ -0,0 +1,583 @@
+package www
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+func TestMaxResponseSize(t *testing.T) {
+
+	var bomb bytes.Buffer
+	zw := gzip.NewWriter(&bomb)
+	zw.Write(make([]byte, 1<<20))
+	zw.Close()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write(bomb.Bytes())
+	}))
+	defer srv.Close()
+
+	const limit = 64 << 10
+	if bomb.Len() >= limit {
+		t.Fatalf("the compressed payload (%d bytes) must fit into the limit", bomb.Len())
+	}
+
+	resp := NewRequest(NewClient().WithMaxResponseSize(limit)).
+		Get(srv.URL, http.Header{"Accept-Encoding": {"gzip"}})
+	if resp.Error() != nil {
+		t.Fatalf("%v", resp.Error())
+	}
+
+	content := resp.Content()
+	if !errors.Is(resp.Error(), ErrBodyTooLarge) {
+		t.Errorf("Error:got %v, want %v", resp.Error(), ErrBodyTooLarge)
+	}
+	if len(content) != limit {
+		t.Errorf("read:got %d bytes, want %d", len(content), limit)
+	}
+}
+
+func TestJSONWithRaw(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"key":"value"}`))
+	}))
+	defer srv.Close()
+
+	resp := NewRequest(NewClient()).Get(srv.URL)
+
+	var data struct{ Key string }
+	raw, err := resp.JSONWithRaw(&data)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if data.Key != "value" {
+		t.Errorf("Key:got %q, want %q", data.Key, "value")
+	}
+	if string(raw) != `{"key":"value"}` || !bytes.Equal(resp.Content(), raw) {
+		t.Errorf("raw:got %q, content %q", raw, resp.Content())
+	}
+}
+
+func TestJSONField(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data":{"items":[{"id":1},{"id":42,"name":"go"}]}}`))
+	}))
+	defer srv.Close()
+
+	resp := NewRequest(NewClient()).Get(srv.URL)
+
+	var id int
+	if err := resp.JSONField("data.items.1.id", &id); err != nil || id != 42 {
+		t.Errorf("id:got %d (%v), want 42", id, err)
+	}
+
+	if err := resp.JSONField("data.items.2.id", &id); !errors.Is(err, ErrorJSONPath) {
+		t.Errorf("missing path:got %v, want %v", err, ErrorJSONPath)
+	}
+
+	var name int
+	if err := resp.JSONField("data.items.1.name", &name); err == nil {
+		t.Errorf("type mismatch:got no error")
+	}
+}
+
+func TestEncoding(t *testing.T) {
+
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write([]byte("hello"))
+	zw.Close()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write(gz.Bytes())
+	}))
+	defer srv.Close()
+
+	t.Run("TRANSPORT", func(t *testing.T) {
+		resp := NewRequest(NewClient()).Get(srv.URL)
+		resp.Content()
+		info := resp.Encoding()
+		if !info.TransportDecompressed || info.ClientDecompressed || info.ContentEncoding != "gzip" {
+			t.Errorf("got %+v", info)
+		}
+	})
+
+	t.Run("CLIENT", func(t *testing.T) {
+		resp := NewRequest(NewClient()).Get(srv.URL, http.Header{"Accept-Encoding": {"gzip"}})
+		if got := resp.Text(); got != "hello" {
+			t.Errorf("body:got %q, want %q", got, "hello")
+		}
+		info := resp.Encoding()
+		if info.TransportDecompressed || !info.ClientDecompressed || info.AcceptEncoding != "gzip" {
+			t.Errorf("got %+v", info)
+		}
+	})
+
+	t.Run("NIL", func(t *testing.T) {
+		var resp *Response
+		if info := resp.Encoding(); info != (EncodingInfo{}) {
+			t.Errorf("got %+v", info)
+		}
+	})
+}
+
+func TestJSONInsensitive(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"UserID":7,"Items":[{"NAME":"go"}]}`))
+	}))
+	defer srv.Close()
+
+	var data map[string]interface{}
+	if err := NewRequest(NewClient()).Get(srv.URL).JSONInsensitive(&data); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if data["userid"] != 7.0 {
+		t.Errorf("userid:got %v, want 7", data["userid"])
+	}
+	item := data["items"].([]interface{})[0].(map[string]interface{})
+	if item["name"] != "go" {
+		t.Errorf("name:got %v, want go", item["name"])
+	}
+}
+
+func TestJSONDecodersGuard(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		if r.URL.Path == "/error" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		w.Write([]byte("<html>oops</html>"))
+	}))
+	defer srv.Close()
+
+	decoders := map[string]func(*Response) error{
+		"JSONWithRaw": func(resp *Response) error {
+			var v interface{}
+			_, err := resp.JSONWithRaw(&v)
+			return err
+		},
+		"JSONInsensitive": func(resp *Response) error {
+			var v interface{}
+			return resp.JSONInsensitive(&v)
+		},
+		"JSONField": func(resp *Response) error {
+			var v interface{}
+			return resp.JSONField("a", &v)
+		},
+	}
+	for name, decode := range decoders {
+		name, decode := name, decode
+		t.Run(strings.ToUpper(name), func(t *testing.T) {
+			var httpErr *HTTPError
+			if err := decode(NewRequest(NewClient()).Get(srv.URL + "/error")); !errors.As(err, &httpErr) {
+				t.Errorf("status:got %v, want an *HTTPError", err)
+			}
+			if err := decode(NewRequest(NewClient()).Get(srv.URL)); !errors.Is(err, ErrorUnexpectedContentType) {
+				t.Errorf("HTML:got %v, want %v", err, ErrorUnexpectedContentType)
+			}
+			if err := decode(&Response{}); !errors.Is(err, ErrorNoResponse) {
+				t.Errorf("no response:got %v, want %v", err, ErrorNoResponse)
+			}
+		})
+	}
+}
+
+func TestEnsure(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte("<html>maintenance</html>"))
+	}))
+	defer srv.Close()
+
+	t.Run("CONTENT TYPE", func(t *testing.T) {
+		resp := NewRequest(NewClient()).Get(srv.URL).
+			EnsureStatus(http.StatusOK).
+			EnsureContentType("application/json", "application/problem+json")
+		if !errors.Is(resp.Error(), ErrorUnexpectedContentType) {
+			t.Errorf("Error:got %v, want %v", resp.Error(), ErrorUnexpectedContentType)
+		}
+
+		resp = NewRequest(NewClient()).Get(srv.URL).EnsureContentType("text/html")
+		if resp.Error() != nil {
+			t.Errorf("%v", resp.Error())
+		}
+	})
+
+	t.Run("STATUS", func(t *testing.T) {
+		resp := NewRequest(NewClient()).Get(srv.URL).EnsureStatus(http.StatusCreated)
+		if !errors.Is(resp.Error(), ErrorUnexpectedStatus) {
+			t.Errorf("Error:got %v, want %v", resp.Error(), ErrorUnexpectedStatus)
+		}
+	})
+}
+
+func TestLines(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("first\nsecond\n" + strings.Repeat("x", 100000) + "\n"))
+	}))
+	defer srv.Close()
+
+	resp := NewRequest(NewClient()).Get(srv.URL)
+	scanner, err := resp.Lines()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	defer resp.Body.Close()
+
+	var lines []string
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if scanner.Err() != nil {
+		t.Fatalf("%v", scanner.Err())
+	}
+	if len(lines) != 3 || lines[1] != "second" || len(lines[2]) != 100000 {
+		t.Errorf("got %d lines", len(lines))
+	}
+
+	failed := &Response{err: ErrorNoResponse}
+	if _, err := failed.Lines(); err != ErrorNoResponse {
+		t.Errorf("Error:got %v, want %v", err, ErrorNoResponse)
+	}
+}
+
+type injectHeader struct {
+	key, value string
+	next       http.RoundTripper
+}
+
+func (t injectHeader) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set(t.key, t.value)
+	return t.next.RoundTrip(req)
+}
+
+func TestRequestHeaders(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	cl := NewClient(&http.Client{
+		Transport: injectHeader{"Authorization", "Bearer token", http.DefaultTransport},
+	})
+	r := NewRequest(cl)
+	resp := r.Get(srv.URL, http.Header{"Accept": {"application/json"}})
+	if resp.Error() != nil {
+		t.Fatalf("%v", resp.Error())
+	}
+
+	sent := resp.RequestHeaders()
+	if sent.Get("Authorization") != "Bearer token" || sent.Get("Accept") != "application/json" {
+		t.Errorf("got %v", sent)
+	}
+	if r.Headers().Get("Authorization") != "" {
+		t.Errorf("the builder headers must not see the injected header")
+	}
+
+	var failed *Response
+	if failed.RequestHeaders() != nil {
+		t.Errorf("nil response:got headers")
+	}
+}
+
+func TestPipeTo(t *testing.T) {
+
+	src := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/csv")
+		w.Write([]byte("a,b\n1,2\n"))
+	}))
+	defer src.Close()
+
+	dst := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		fmt.Fprintf(w, "%s|%s", r.Header.Get("Content-Type"), body)
+	}))
+	defer dst.Close()
+
+	var closed []string
+	source := NewClient().WithBodyTransform(func(body io.ReadCloser) io.ReadCloser {
+		return closeRecorder{body, body, "source", &closed}
+	})
+	cl := NewClient()
+
+	t.Run("FORWARD", func(t *testing.T) {
+		closed = nil
+		got := NewRequest(source).Get(src.URL).PipeTo(NewRequest(cl)).Post(dst.URL).Text()
+		if want := "text/csv|a,b\n1,2\n"; got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+		if len(closed) == 0 {
+			t.Errorf("the source body is not closed")
+		}
+	})
+
+	t.Run("OVERRIDE", func(t *testing.T) {
+		got := NewRequest(cl).Get(src.URL).PipeTo(NewRequest(cl).ContentType("text/plain")).Post(dst.URL).Text()
+		if want := "text/plain|a,b\n1,2\n"; got != want {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	})
+
+	t.Run("ERROR", func(t *testing.T) {
+		resp := (&Response{err: ErrorEmptyURL}).PipeTo(NewRequest(cl)).Post(dst.URL)
+		if !errors.Is(resp.Error(), ErrorEmptyURL) {
+			t.Errorf("Error:got %v, want %v", resp.Error(), ErrorEmptyURL)
+		}
+	})
+}
+
+func TestBase64(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.Query().Get("body")))
+	}))
+	defer srv.Close()
+
+	// "hello?>" has a + and a / in the standard alphabet
+	for name, body := range map[string]string{
+		"STD":     "aGVsbG8/Pg==",
+		"URL":     "aGVsbG8_Pg",
+		"WRAPPED": "aGVs\r\nbG8/\nPg==\n",
+		"RAW":     "aGVsbG8/Pg",
+		"URL PAD": "aGVsbG8_Pg==",
+	} {
+		t.Run(name, func(t *testing.T) {
+			resp := NewRequest(NewClient()).WithQuery(&url.Values{"body": {body}}).Get(srv.URL)
+			data, err := resp.Base64()
+			if err != nil {
+				t.Fatalf("%v", err)
+			}
+			if string(data) != "hello?>" {
+				t.Errorf("got %q, want %q", data, "hello?>")
+			}
+			if resp.Text() != body {
+				t.Errorf("Text:got %q, want %q", resp.Text(), body)
+			}
+		})
+	}
+
+	t.Run("INVALID", func(t *testing.T) {
+		resp := NewRequest(NewClient()).WithQuery(&url.Values{"body": {"not base64!"}}).Get(srv.URL)
+		if _, err := resp.Base64(); !errors.Is(err, ErrorInvalidBase64) {
+			t.Errorf("got %v, want %v", err, ErrorInvalidBase64)
+		}
+	})
+}
+
+func TestBytesRead(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// chunked, without a Content-Length
+		w.Write([]byte("hello"))
+		w.(http.Flusher).Flush()
+		w.Write([]byte(" world"))
+	}))
+	defer srv.Close()
+
+	var resp *Response
+	if got := resp.BytesRead(); got != 0 {
+		t.Errorf("nil:got %d, want 0", got)
+	}
+
+	resp = NewRequest(NewClient()).Get(srv.URL)
+	if got := resp.BytesRead(); got != 0 {
+		t.Errorf("before read:got %d, want 0", got)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if got := resp.BytesRead(); got != 11 {
+		t.Errorf("got %d, want 11", got)
+	}
+}
+
+func TestJson(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/html":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html></html>"))
+		case "/error":
+			w.Header().Set("Content-Type", "application/problem+json")
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"title":"not found"}`))
+		default:
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			w.Write([]byte(`{"name":"www","stars":42}`))
+		}
+	}))
+	defer srv.Close()
+
+	var data struct {
+		Name  string `json:"name"`
+		Stars int    `json:"stars"`
+	}
+
+	resp := NewRequest(NewClient()).Get(srv.URL)
+	if err := resp.Json(&data); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if data.Name != "www" || data.Stars != 42 {
+		t.Errorf("got %+v", data)
+	}
+	var again map[string]interface{}
+	if err := resp.JSON(&again); err != nil || again["name"] != "www" {
+		t.Errorf("again:got %v, %v", again, err)
+	}
+	if raw, err := resp.Bytes(); err != nil || string(raw) != `{"name":"www","stars":42}` {
+		t.Errorf("Bytes:got %q, %v", raw, err)
+	}
+
+	resp = NewRequest(NewClient()).Get(srv.URL + "/error")
+	if err := resp.Json(&data); !errors.Is(err, ErrorUnexpectedStatus) {
+		t.Errorf("status:got %v, want %v", err, ErrorUnexpectedStatus)
+	}
+	if resp.Text() != `{"title":"not found"}` {
+		t.Errorf("the body is not kept: %q", resp.Text())
+	}
+
+	if err := NewRequest(NewClient()).Get(srv.URL + "/html").Json(&data); !errors.Is(err, ErrorUnexpectedContentType) {
+		t.Errorf("content type:got %v, want %v", err, ErrorUnexpectedContentType)
+	}
+	if err := NewRequest(NewClient()).Get("").Json(&data); err == nil {
+		t.Errorf("failed request:got nil error")
+	}
+}
+
+func TestStrictContentLength(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/honest" {
+			w.Write([]byte("complete"))
+			return
+		}
+		conn, buf, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		buf.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 100\r\n\r\ntruncated")
+		buf.Flush()
+	}))
+	defer srv.Close()
+
+	cl := NewClient().WithStrictContentLength()
+
+	_, err := NewRequest(cl).Get(srv.URL).Bytes()
+	if !errors.Is(err, ErrContentLengthMismatch) {
+		t.Errorf("Error:got %v, want %v", err, ErrContentLengthMismatch)
+	}
+	if err == nil || !strings.Contains(err.Error(), "got 9 bytes, want 100") {
+		t.Errorf("the error has no counts: %v", err)
+	}
+
+	if data, err := NewRequest(cl).Get(srv.URL + "/honest").Bytes(); err != nil || string(data) != "complete" {
+		t.Errorf("honest:got %q, %v", data, err)
+	}
+}
+
+func TestDecompress(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var zw io.WriteCloser
+		switch r.URL.Path {
+		case "/deflate":
+			w.Header().Set("Content-Encoding", "deflate")
+			zw = zlib.NewWriter(w)
+		case "/raw-deflate":
+			w.Header().Set("Content-Encoding", "deflate")
+			zw, _ = flate.NewWriter(w, flate.DefaultCompression)
+		case "/br":
+			w.Header().Set("Content-Encoding", "br")
+			zw = brotli.NewWriter(w)
+		case "/unknown":
+			w.Header().Set("Content-Encoding", "zstd")
+			w.Write([]byte("opaque"))
+			return
+		default:
+			w.Header().Set("Content-Encoding", "gzip")
+			if r.Method == http.MethodHead {
+				return
+			}
+			zw = gzip.NewWriter(w)
+		}
+		zw.Write([]byte("hello"))
+		zw.Close()
+	}))
+	defer srv.Close()
+
+	explicit := http.Header{"Accept-Encoding": {"gzip, deflate, br"}}
+
+	for path, encoding := range map[string]string{"/gzip": "gzip", "/deflate": "deflate", "/raw-deflate": "deflate", "/br": "br"} {
+		path, encoding := path, encoding
+		t.Run(path, func(t *testing.T) {
+			resp := NewRequest(NewClient()).Get(srv.URL+path, explicit)
+			data, err := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil || string(data) != "hello" {
+				t.Errorf("Body:got %q, %v", data, err)
+			}
+			if resp.Header.Get("Content-Encoding") != "" || resp.ContentLength != -1 {
+				t.Errorf("headers are not stripped: %v, %d", resp.Header, resp.ContentLength)
+			}
+			if info := resp.Encoding(); info.ContentEncoding != encoding || !info.ClientDecompressed {
+				t.Errorf("Encoding:got %+v", info)
+			}
+		})
+	}
+
+	t.Run("UNKNOWN", func(t *testing.T) {
+		resp := NewRequest(NewClient()).Get(srv.URL+"/unknown", explicit)
+		if got := resp.Text(); got != "opaque" || resp.Header.Get("Content-Encoding") != "zstd" {
+			t.Errorf("got %q, %v", got, resp.Header)
+		}
+	})
+
+	t.Run("HEAD", func(t *testing.T) {
+		resp := NewRequest(NewClient()).Do(http.MethodHead, srv.URL, explicit)
+		if data, err := resp.Bytes(); err != nil || len(data) != 0 {
+			t.Errorf("got %q, %v", data, err)
+		}
+	})
+
+	t.Run("ACCEPT BR", func(t *testing.T) {
+		resp := NewRequest(NewClient().WithAcceptEncoding("br")).Get(srv.URL + "/br")
+		if got := resp.Text(); got != "hello" || resp.Encoding().ContentEncoding != "br" {
+			t.Errorf("got %q, %+v", got, resp.Encoding())
+		}
+	})
+
+	t.Run("RAW", func(t *testing.T) {
+		resp := NewRequest(NewClient().WithRawBodies()).Get(srv.URL, explicit)
+		zr, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		if data, _ := io.ReadAll(zr); string(data) != "hello" || resp.Header.Get("Content-Encoding") != "gzip" {
+			t.Errorf("got %q, %v", data, resp.Header)
+		}
+	})
+}
@@ -0,0 +1,97 @@
+package www
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Resumable makes a GET download resilient: when reading the body fails
+// midway, the request is re-issued up to attempts times with a Range
+// header asking for the rest, and reading goes on transparently. It needs
+// a server that supports byte ranges (Accept-Ranges: bytes), the resumed
+// part is validated with If-Range against the ETag or Last-Modified of the
+// first response. Otherwise, and for a gzip body decompressed by net/http,
+// the read error is returned as is.
+func (r *Request) Resumable(attempts int) *Request {
+	r.resume = attempts
+	return r
+}
+
+func (r *Request) resumeBody(resp *http.Response) {
+	// the offsets of a body net/http decompressed do not match the
+	// ranges of the gzip stream
+	if r.resume <= 0 || resp.StatusCode != http.StatusOK || resp.Uncompressed ||
+		(r.Request.Method != http.MethodGet) ||
+		resp.Header.Get("Accept-Ranges") != "bytes" {
+		return
+	}
+	resp.Body = &resumeReader{
+		body:     resp.Body,
+		req:      r,
+		left:     r.resume,
+		validate: validatorOf(resp),
+	}
+}
+
+func validatorOf(resp *http.Response) string {
+	if etag := resp.Header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+	return resp.Header.Get("Last-Modified")
+}
+
+type resumeReader struct {
+	body     io.ReadCloser
+	req      *Request
+	read     int64
+	left     int
+	validate string
+}
+
+func (rr *resumeReader) Read(p []byte) (int, error) {
+	for {
+		n, err := rr.body.Read(p)
+		rr.read += int64(n)
+		if err == nil || err == io.EOF || rr.left == 0 {
+			return n, err
+		}
+
+		rr.left--
+		if rerr := rr.resume(); rerr != nil {
+			return n, err
+		}
+		if n > 0 {
+			return n, nil
+		}
+	}
+}
+
+func (rr *resumeReader) resume() error {
+	prev := rr.req.Request
+	req := prev.Clone(prev.Context())
+	req.Header.Set("Range", "bytes="+strconv.FormatInt(rr.read, 10)+"-")
+	if rr.validate != "" {
+		req.Header.Set("If-Range", rr.validate)
+	}
+
+	resp, err := rr.req.client.Do(req)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusPartialContent ||
+		!strings.HasPrefix(resp.Header.Get("Content-Range"), fmt.Sprintf("bytes %d-", rr.read)) {
+		resp.Body.Close()
+		return fmt.Errorf("the server cannot resume at byte %d: %s", rr.read, resp.Status)
+	}
+
+	rr.body.Close()
+	rr.body = resp.Body
+	return nil
+}
+
+func (rr *resumeReader) Close() error {
+	return rr.body.Close()
+}
@@ -0,0 +1,90 @@
+package www
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestResumable(t *testing.T) {
+
+	data := bytes.Repeat([]byte("0123456789"), 1000)
+
+	newServer := func(ranges bool) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Range") != "" {
+				w.Header().Set("ETag", `"v1"`)
+				http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(data))
+				return
+			}
+			// the connection breaks after the first 4000 bytes
+			conn, buf, _ := w.(http.Hijacker).Hijack()
+			fmt.Fprintf(buf, "HTTP/1.1 200 OK\r\nContent-Length: %d\r\nETag: \"v1\"\r\n", len(data))
+			if ranges {
+				buf.WriteString("Accept-Ranges: bytes\r\n")
+			}
+			buf.WriteString("\r\n")
+			buf.Write(data[:4000])
+			buf.Flush()
+			conn.Close()
+		}))
+	}
+
+	t.Run("RESUMED", func(t *testing.T) {
+		srv := newServer(true)
+		defer srv.Close()
+
+		resp := NewRequest(NewClient()).Resumable(1).Get(srv.URL)
+		content := resp.Content()
+		if resp.Error() != nil {
+			t.Fatalf("%v", resp.Error())
+		}
+		if !bytes.Equal(content, data) {
+			t.Errorf("got %d bytes, want %d", len(content), len(data))
+		}
+	})
+
+	t.Run("TRANSPORT GZIP", func(t *testing.T) {
+		var gz bytes.Buffer
+		zw := gzip.NewWriter(&gz)
+		zw.Write(data)
+		zw.Close()
+
+		var ranges int
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Range") != "" {
+				ranges++
+				http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(gz.Bytes()))
+				return
+			}
+			conn, buf, _ := w.(http.Hijacker).Hijack()
+			fmt.Fprintf(buf, "HTTP/1.1 200 OK\r\nContent-Length: %d\r\nContent-Encoding: gzip\r\n"+
+				"Accept-Ranges: bytes\r\n\r\n", gz.Len())
+			buf.Write(gz.Bytes()[:gz.Len()/2])
+			buf.Flush()
+			conn.Close()
+		}))
+		defer srv.Close()
+
+		resp := NewRequest(NewClient()).Resumable(1).Get(srv.URL)
+		resp.Content()
+		if resp.Error() == nil || ranges != 0 {
+			t.Errorf("got %v after %d range requests, want a read error and none", resp.Error(), ranges)
+		}
+	})
+
+	t.Run("NO RANGES", func(t *testing.T) {
+		srv := newServer(false)
+		defer srv.Close()
+
+		resp := NewRequest(NewClient()).Resumable(1).Get(srv.URL)
+		resp.Content()
+		if resp.Error() == nil {
+			t.Errorf("a truncated body must fail without range support")
+		}
+	})
+}
@@ -0,0 +1,245 @@
+package www
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy makes a client send a failed request again. Only requests
+// with an idempotent method (or an Idempotency-Key header) are retried,
+// their body must be replayable: a buffered one (Json, WithForm, ...),
+// a WithSeeker one or files attached with AttachFile. A streaming body is
+// sent once, the failure is then reported with ErrorBodyNotReplay.
+type RetryPolicy struct {
+	// retries after the first attempt
+	MaxRetries int
+	// reports whether an attempt failed, DefaultRetryOn when nil,
+	// ClassifyError tells the kind of err
+	RetryOn func(*http.Response, error) bool
+	// the wait before the retry number attempt (from 1), DefaultBackoff
+	// when nil
+	Backoff func(attempt int) time.Duration
+}
+
+// WithRetry sets the retry policy of the client, without it every
+// request is sent once. The Retry-After header of a 429 or 503 response
+// (seconds or a date) replaces the backoff of the next attempt, bound it
+// with Request.RetryDeadline.
+func (cl *StandardClient) WithRetry(policy RetryPolicy) *StandardClient {
+	cl.retry = &policy
+	return cl
+}
+
+// Retry overrides the retry policy of the client for this request,
+// RetryPolicy{} disables the retries.
+func (r *Request) Retry(policy RetryPolicy) *Request {
+	r.retry = &policy
+	return r
+}
+
+func (r *Request) retryPolicy() *RetryPolicy {
+	if r.retry != nil {
+		return r.retry
+	}
+	return r.client.retry
+}
+
+// DefaultRetryOn retries connection errors, timeouts waiting for the
+// response and the 429, 502, 503 and 504 responses.
+func DefaultRetryOn(resp *http.Response, err error) bool {
+	if err != nil {
+		return isConnectionError(err)
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+func isConnectionError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, ErrorResponseTimeout) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return true
+	}
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && (dnsErr.IsTemporary || dnsErr.IsTimeout)
+}
+
+// Failures told apart by ClassifyError, for a RetryOn that treats them
+// differently.
+const (
+	FailureDNS      = "dns"      // the host name was not resolved
+	FailureConnect  = "connect"  // the connection was not established
+	FailureTimeout  = "timeout"  // no response in time, the request may have been processed
+	FailureRead     = "read"     // the connection broke after the request was sent
+	FailureCanceled = "canceled" // the context of the request was cancelled
+	FailureOther    = "other"
+)
+
+// ClassifyError returns the Failure kind of an error passed to RetryOn,
+// "" for a nil one:
+//
+//	RetryOn: func(resp *http.Response, err error) bool {
+//		switch www.ClassifyError(err) {
+//		case www.FailureConnect:
+//			return true
+//		case www.FailureRead, www.FailureTimeout:
+//			return false
+//		}
+//		return www.DefaultRetryOn(resp, err)
+//	},
+func ClassifyError(err error) string {
+	if err == nil {
+		return ""
+	}
+	if errors.Is(err, context.Canceled) {
+		return FailureCanceled
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return FailureDNS
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" || errors.Is(err, syscall.ECONNREFUSED) {
+		return FailureConnect
+	}
+	var netErr net.Error
+	if errors.Is(err, ErrorResponseTimeout) || errors.Is(err, context.DeadlineExceeded) ||
+		errors.As(err, &netErr) && netErr.Timeout() {
+		return FailureTimeout
+	}
+	if opErr != nil || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) {
+		return FailureRead
+	}
+	return FailureOther
+}
+
+// DefaultBackoff doubles the wait from 100ms for every retry, up to 10s.
+func DefaultBackoff(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	if attempt > 7 {
+		return 10 * time.Second
+	}
+	d := 100 * time.Millisecond << uint(attempt-1)
+	if d > 10*time.Second {
+		d = 10 * time.Second
+	}
+	return d
+}
+
+// Jitter randomizes the waits of backoff between half and all of them,
+// so the clients failed together do not retry together:
+//
+//	RetryPolicy{MaxRetries: 3, Backoff: www.Jitter(www.DefaultBackoff)}
+func Jitter(backoff func(attempt int) time.Duration) func(attempt int) time.Duration {
+	return func(attempt int) time.Duration {
+		d := backoff(attempt)
+		if d <= 1 {
+			return d
+		}
+		return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+	}
+}
+
+// wait returns the wait before the retry number attempt, resp is the
+// failed response of the previous one, nil after an error.
+func (p *RetryPolicy) wait(attempt int, resp *http.Response, now time.Time) time.Duration {
+	if resp != nil && (resp.StatusCode == http.StatusTooManyRequests ||
+		resp.StatusCode == http.StatusServiceUnavailable) {
+		if d, ok := retryAfter(resp.Header.Get("Retry-After"), now); ok {
+			return d
+		}
+	}
+	if p.Backoff == nil {
+		return DefaultBackoff(attempt)
+	}
+	return p.Backoff(attempt)
+}
+
+func retryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	if d := date.Sub(now); d > 0 {
+		return d, true
+	}
+	return 0, true
+}
+
+// methods that may be sent again without changing the result
+var idempotentMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodOptions: true,
+	http.MethodTrace:   true,
+	http.MethodPut:     true,
+	http.MethodDelete:  true,
+}
+
+// shouldRetry reports whether the attempt failed and may be retried by
+// the policy of the client.
+func (r *Request) shouldRetry(attempt int, resp *http.Response, err error) bool {
+	policy := r.retryPolicy()
+	if policy == nil || attempt >= policy.MaxRetries || r.context().Err() != nil {
+		return false
+	}
+	if !idempotentMethods[r.Request.Method] && r.Request.Header.Get("Idempotency-Key") == "" {
+		return false
+	}
+
+	retryOn := policy.RetryOn
+	if retryOn == nil {
+		retryOn = DefaultRetryOn
+	}
+	return retryOn(resp, err) || r.errorMatches(err)
+}
+
+func replayable(req *http.Request) bool {
+	return req.GetBody != nil || req.Body == nil || req.Body == http.NoBody
+}
+
+func notRetried(resp *http.Response, err error) error {
+	if err != nil {
+		return fmt.Errorf("%w, not retried: %v", ErrorBodyNotReplay, err)
+	}
+	return fmt.Errorf("%w, not retried after %s", ErrorBodyNotReplay, resp.Status)
+}
+
+// drainBody reads a little of the body before closing it, so the
+// connection can be reused.
+func drainBody(body io.ReadCloser) {
+	io.CopyN(ioutil.Discard, body, 4<<10)
+	body.Close()
+}
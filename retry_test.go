@@ -0,0 +1,272 @@
+package www
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRetry(t *testing.T) {
+
+	var hits int
+	var bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		if hits%3 != 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintf(w, "ok")
+	}))
+	defer srv.Close()
+
+	clock := &fakeClock{}
+	cl := NewClient().WithClock(clock).WithRetry(RetryPolicy{MaxRetries: 3})
+
+	t.Run("REPLAY", func(t *testing.T) {
+		hits, bodies, clock.slept = 0, nil, nil
+		var retries []int
+		resp := NewRequest(cl).
+			JSON(map[string]string{"key": "value"}).
+			OnRetry(func(attempt int, resp *http.Response, err error) {
+				retries = append(retries, attempt)
+				if resp.StatusCode != http.StatusServiceUnavailable {
+					t.Errorf("OnRetry:got %d", resp.StatusCode)
+				}
+			}).
+			Put(srv.URL)
+
+		if got := resp.Text(); got != "ok" {
+			t.Errorf("got %q, want %q", got, "ok")
+		}
+		if strings.Join(bodies, "|") != `{"key":"value"}|{"key":"value"}|{"key":"value"}` {
+			t.Errorf("bodies:got %q", bodies)
+		}
+		if fmt.Sprint(retries) != "[1 2]" {
+			t.Errorf("retries:got %v, want [1 2]", retries)
+		}
+		if fmt.Sprint(clock.slept) != "[100ms 200ms]" {
+			t.Errorf("backoff:got %v, want [100ms 200ms]", clock.slept)
+		}
+	})
+
+	t.Run("NOT IDEMPOTENT", func(t *testing.T) {
+		hits = 0
+		resp := NewRequest(cl).JSON(map[string]string{"key": "value"}).Post(srv.URL)
+		if resp.StatusCode != http.StatusServiceUnavailable || hits != 1 {
+			t.Errorf("got %d after %d attempts", resp.StatusCode, hits)
+		}
+
+		hits = 0
+		resp = NewRequest(cl).Post(srv.URL, http.Header{"Idempotency-Key": {"key"}})
+		if resp.Text() != "ok" || hits != 3 {
+			t.Errorf("Idempotency-Key:got %q after %d attempts", resp.Text(), hits)
+		}
+	})
+
+	t.Run("STREAMING BODY", func(t *testing.T) {
+		hits = 0
+		resp := NewRequest(cl).WithFile(io.MultiReader(strings.NewReader("data"))).Put(srv.URL)
+		if !errors.Is(resp.Error(), ErrorBodyNotReplay) || hits != 1 {
+			t.Errorf("got %v after %d attempts", resp.Error(), hits)
+		}
+	})
+
+	t.Run("NO POLICY", func(t *testing.T) {
+		hits = 0
+		resp := NewRequest(NewClient()).Get(srv.URL)
+		if resp.StatusCode != http.StatusServiceUnavailable || hits != 1 {
+			t.Errorf("got %d after %d attempts", resp.StatusCode, hits)
+		}
+	})
+
+	t.Run("ERROR MATCH", func(t *testing.T) {
+		var attempts int
+		failing := NewClient(&http.Client{Transport: roundTripFunc(func(*http.Request) (*http.Response, error) {
+			attempts++
+			return nil, errors.New("weird proxy failure")
+		})}).WithClock(clock).WithRetry(RetryPolicy{MaxRetries: 2})
+
+		NewRequest(failing).Get(srv.URL)
+		if attempts != 1 {
+			t.Errorf("untyped:got %d attempts, want 1", attempts)
+		}
+		attempts = 0
+		NewRequest(failing).RetryOnErrorMatch("proxy failure").Get(srv.URL)
+		if attempts != 3 {
+			t.Errorf("matched:got %d attempts, want 3", attempts)
+		}
+	})
+}
+
+func TestRetryDeadline(t *testing.T) {
+
+	var hits int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprintf(w, "attempt %d", hits)
+	}))
+	defer srv.Close()
+
+	clock := &fakeClock{}
+	cl := NewClient().WithClock(clock).WithRetry(RetryPolicy{MaxRetries: 10})
+
+	// 100ms, 200ms and 400ms fit in 1s, the next 800ms wait does not
+	resp := NewRequest(cl).RetryDeadline(time.Second).Get(srv.URL)
+	if hits != 4 {
+		t.Errorf("attempts:got %d, want 4", hits)
+	}
+	if fmt.Sprint(clock.slept) != "[100ms 200ms 400ms]" {
+		t.Errorf("backoff:got %v, want [100ms 200ms 400ms]", clock.slept)
+	}
+	if resp.StatusCode != http.StatusServiceUnavailable || resp.Text() != "attempt 4" {
+		t.Errorf("last result:got %v, %q", resp.Error(), resp.Text())
+	}
+
+	hits, clock.slept = 0, nil
+	NewRequest(cl).Get(srv.URL)
+	if hits != 11 {
+		t.Errorf("no deadline:got %d attempts, want 11", hits)
+	}
+}
+
+func TestDefaultBackoff(t *testing.T) {
+	for attempt, want := range map[int]time.Duration{
+		1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 7: 6400 * time.Millisecond, 8: 10 * time.Second,
+	} {
+		if got := DefaultBackoff(attempt); got != want {
+			t.Errorf("%d:got %v, want %v", attempt, got, want)
+		}
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (fn roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return fn(req)
+}
+
+func TestClassifyError(t *testing.T) {
+
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, _, _ := w.(http.Hijacker).Hijack()
+		conn.Close()
+	}))
+	defer broken.Close()
+	refused := httptest.NewServer(http.NotFoundHandler())
+	refused.Close()
+
+	faulty := func(err error) *StandardClient {
+		return NewClient().WithTransport(roundTripFunc(func(*http.Request) (*http.Response, error) {
+			return nil, err
+		}))
+	}
+	timeout := &net.OpError{Op: "read", Net: "tcp", Err: os.ErrDeadlineExceeded}
+
+	for _, test := range []struct {
+		name string
+		cl   *StandardClient
+		uri  string
+		want string
+	}{
+		{"DNS", NewClient(), "http://www-test.invalid/", FailureDNS},
+		{"CONNECT", NewClient(), refused.URL, FailureConnect},
+		{"READ", NewClient(), broken.URL, FailureRead},
+		{"TIMEOUT", faulty(timeout), broken.URL, FailureTimeout},
+		{"RESPONSE TIMEOUT", faulty(ErrorResponseTimeout), broken.URL, FailureTimeout},
+		{"RESET", faulty(syscall.ECONNRESET), broken.URL, FailureRead},
+		{"OTHER", faulty(errors.New("proxy failure")), broken.URL, FailureOther},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			var got []string
+			cl := test.cl.WithClock(&fakeClock{}).WithRetry(RetryPolicy{
+				MaxRetries: 1,
+				RetryOn: func(resp *http.Response, err error) bool {
+					got = append(got, ClassifyError(err))
+					return false
+				},
+			})
+			NewRequest(cl).Get(test.uri)
+			if fmt.Sprint(got) != "["+test.want+"]" {
+				t.Errorf("ClassifyError:got %v, want [%s]", got, test.want)
+			}
+		})
+	}
+
+	if got := ClassifyError(nil); got != "" {
+		t.Errorf("nil:got %q, want \"\"", got)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+
+	var hits int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		if hits == 1 {
+			w.Header().Set("Retry-After", r.URL.Query().Get("after"))
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		fmt.Fprintf(w, "ok")
+	}))
+	defer srv.Close()
+
+	for after, want := range map[string]string{
+		"3": "[3s]",
+		time.Unix(5, 0).UTC().Format(http.TimeFormat): "[5s]",
+		"soon": "[100ms]",
+	} {
+		hits = 0
+		clock := &fakeClock{now: time.Unix(0, 0)}
+		cl := NewClient().WithClock(clock).WithRetry(RetryPolicy{MaxRetries: 1})
+		resp := NewRequest(cl).Query(url.Values{"after": {after}}).Get(srv.URL)
+		if resp.Text() != "ok" || fmt.Sprint(clock.slept) != want {
+			t.Errorf("%s:got %q after %v, want %s", after, resp.Text(), clock.slept, want)
+		}
+	}
+}
+
+func TestRequestRetry(t *testing.T) {
+
+	var hits int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	clock := &fakeClock{}
+	NewRequest(NewClient().WithClock(clock)).Retry(RetryPolicy{MaxRetries: 2}).Get(srv.URL)
+	if hits != 3 {
+		t.Errorf("override:got %d attempts, want 3", hits)
+	}
+
+	hits = 0
+	cl := NewClient().WithClock(clock).WithRetry(RetryPolicy{MaxRetries: 2})
+	NewRequest(cl).Retry(RetryPolicy{}).Get(srv.URL)
+	if hits != 1 {
+		t.Errorf("disabled:got %d attempts, want 1", hits)
+	}
+}
+
+func TestJitter(t *testing.T) {
+	backoff := Jitter(DefaultBackoff)
+	for i := 0; i < 100; i++ {
+		if d := backoff(2); d < 100*time.Millisecond || d > 200*time.Millisecond {
+			t.Fatalf("got %v, want between 100ms and 200ms", d)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package www
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+// Sign calls sign with the prepared request and the exact body bytes
+// that are sent, to add a signature header computed over them. The body
+// is buffered for it, a streaming one included, after Compress and the
+// multipart or JSON encoding, and every redirect or retry resends the
+// same bytes. sign runs last, after the OnBeforeRequest hooks of the
+// client, an error it returns fails the request.
+func (r *Request) Sign(sign func(req *http.Request, body []byte) error) *Request {
+	r.sign = sign
+	return r
+}
+
+func (r *Request) signBody() {
+	var body []byte
+	if r.Request.Body != nil && r.Request.Body != http.NoBody {
+		var err error
+		body, err = ioutil.ReadAll(r.Request.Body)
+		r.Request.Body.Close()
+		if err != nil {
+			r.err = err
+			return
+		}
+		r.Request.Body = ioutil.NopCloser(bytes.NewReader(body))
+		r.Request.ContentLength = int64(len(body))
+		r.Request.GetBody = func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader(body)), nil
+		}
+	}
+
+	if err := r.sign(r.Request, body); err != nil {
+		r.err = err
+	}
+}
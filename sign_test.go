@@ -0,0 +1,75 @@
+package www
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSign(t *testing.T) {
+	var hits int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		body, _ := ioutil.ReadAll(r.Body)
+		sum := sha256.Sum256(body)
+		if r.Header.Get("X-Body-SHA256") != hex.EncodeToString(sum[:]) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path == "/flaky" && hits == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(r.Header.Get("Content-Encoding")))
+	}))
+	defer srv.Close()
+
+	sign := func(req *http.Request, body []byte) error {
+		sum := sha256.Sum256(body)
+		req.Header.Set("X-Body-SHA256", hex.EncodeToString(sum[:]))
+		return nil
+	}
+
+	t.Run("COMPRESSED", func(t *testing.T) {
+		resp := NewRequest(NewClient()).Json(map[string]string{"a": "b"}).Compress().Sign(sign).Post(srv.URL)
+		if resp.StatusCode != http.StatusOK || resp.Text() != "gzip" {
+			t.Errorf("status:got %d %q, want 200 \"gzip\"", resp.StatusCode, resp.Text())
+		}
+	})
+
+	t.Run("STREAMED", func(t *testing.T) {
+		resp := NewRequest(NewClient()).WithFile(strings.NewReader("streamed")).Sign(sign).Post(srv.URL)
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("status:got %d, want 200", resp.StatusCode)
+		}
+	})
+
+	t.Run("NO BODY", func(t *testing.T) {
+		resp := NewRequest(NewClient()).Sign(sign).Get(srv.URL)
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("status:got %d, want 200", resp.StatusCode)
+		}
+	})
+
+	t.Run("RETRIED", func(t *testing.T) {
+		hits = 0
+		cl := NewClient().WithClock(&fakeClock{}).WithRetry(RetryPolicy{MaxRetries: 2})
+		resp := NewRequest(cl).WithFile(strings.NewReader("replayed")).Sign(sign).Put(srv.URL + "/flaky")
+		if resp.StatusCode != http.StatusOK || hits != 2 {
+			t.Errorf("retry:got %d after %d attempts, want 200 after 2", resp.StatusCode, hits)
+		}
+	})
+
+	t.Run("ERROR", func(t *testing.T) {
+		failed := errors.New("no key")
+		resp := NewRequest(NewClient()).Sign(func(*http.Request, []byte) error { return failed }).Get(srv.URL)
+		if !errors.Is(resp.Error(), failed) {
+			t.Errorf("error:got %v, want %v", resp.Error(), failed)
+		}
+	})
+}
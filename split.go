@@ -0,0 +1,143 @@
+package www
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// splitMemoryLimit is the size of the buffered body of Split kept in
+// memory, a larger body is buffered in a temporary file.
+const splitMemoryLimit = 1 << 20
+
+// Split returns two independent readers over the body, e.g. to decode it
+// and compute its checksum with one fetch. The readers can be read one
+// after the other or concurrently. The body read so far is buffered until
+// both readers are closed, in memory up to 1 MiB and then in a temporary
+// file. The body belongs to the readers, both must be closed.
+func (resp *Response) Split() (io.ReadCloser, io.ReadCloser, error) {
+	if resp.err != nil {
+		return nil, nil, resp.err
+	}
+	if resp.Response == nil {
+		return nil, nil, ErrorNoResponse
+	}
+	if resp.content != nil {
+		return ioutil.NopCloser(bytes.NewReader(resp.content)),
+			ioutil.NopCloser(bytes.NewReader(resp.content)), nil
+	}
+
+	reader, err := resp.bodyReader()
+	if err != nil {
+		return nil, nil, err
+	}
+	s := &splitBody{src: reader, body: resp.Body, open: 2}
+	resp.Body = http.NoBody
+	return &splitReader{s: s}, &splitReader{s: s}, nil
+}
+
+type splitBody struct {
+	mu   sync.Mutex
+	src  io.Reader
+	body io.Closer
+	mem  bytes.Buffer
+	file *os.File
+	size int64
+	err  error // of src, io.EOF at the end of the body
+	open int
+}
+
+// fill reads the next chunk of the body into the buffer.
+func (s *splitBody) fill(n int) {
+	if n < 32*1024 {
+		n = 32 * 1024
+	}
+	chunk := make([]byte, n)
+	n, s.err = s.src.Read(chunk)
+	if n == 0 {
+		return
+	}
+	if s.file == nil && s.mem.Len()+n > splitMemoryLimit {
+		if s.file, s.err = ioutil.TempFile("", "www-split-*"); s.err != nil {
+			s.file = nil
+			return
+		}
+		if _, s.err = s.file.Write(s.mem.Bytes()); s.err != nil {
+			return
+		}
+		s.mem = bytes.Buffer{}
+	}
+	if s.file != nil {
+		if _, err := s.file.WriteAt(chunk[:n], s.size); err != nil {
+			s.err = err
+			return
+		}
+	} else {
+		s.mem.Write(chunk[:n])
+	}
+	s.size += int64(n)
+}
+
+func (s *splitBody) readAt(p []byte, off int64) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if off == s.size && s.err == nil {
+		s.fill(len(p))
+	}
+	if off == s.size {
+		return 0, s.err
+	}
+	if s.size-off < int64(len(p)) {
+		p = p[:s.size-off]
+	}
+	if s.file != nil {
+		return s.file.ReadAt(p, off)
+	}
+	return copy(p, s.mem.Bytes()[off:]), nil
+}
+
+func (s *splitBody) close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.open--
+	if s.open > 0 {
+		return nil
+	}
+	err := s.body.Close()
+	if s.file != nil {
+		s.file.Close()
+		os.Remove(s.file.Name())
+	}
+	return err
+}
+
+type splitReader struct {
+	s      *splitBody
+	off    int64
+	closed bool
+}
+
+func (r *splitReader) Read(p []byte) (int, error) {
+	if r.closed {
+		return 0, os.ErrClosed
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	n, err := r.s.readAt(p, r.off)
+	r.off += int64(n)
+	return n, err
+}
+
+func (r *splitReader) Close() error {
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	return r.s.close()
+}
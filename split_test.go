@@ -0,0 +1,93 @@
+package www
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+
+	small := `[{"id":1},{"id":2}]`
+	large := "[" + strings.Repeat(`{"id":1},`, 300000) + `{"id":2}]`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/large" {
+			w.Write([]byte(large))
+			return
+		}
+		w.Write([]byte(small))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name string
+		path string
+		body string
+	}{
+		{"MEMORY", "/small", small},
+		{"TEMP FILE", "/large", large},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmp := t.TempDir()
+			t.Setenv("TMPDIR", tmp)
+
+			decoded, hashed, err := NewRequest(NewClient()).Get(srv.URL + tt.path).Split()
+			if err != nil {
+				t.Fatalf("%v", err)
+			}
+
+			var records []struct{ ID int }
+			var decodeErr error
+			var wg sync.WaitGroup
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer decoded.Close()
+				decodeErr = json.NewDecoder(decoded).Decode(&records)
+			}()
+			h := sha256.New()
+			_, copyErr := io.Copy(h, hashed)
+			hashed.Close()
+			wg.Wait()
+
+			if decodeErr != nil || copyErr != nil {
+				t.Fatalf("decode:%v, copy:%v", decodeErr, copyErr)
+			}
+			if len(records) == 0 || records[len(records)-1].ID != 2 {
+				t.Errorf("decoded %d records", len(records))
+			}
+			if got, want := fmt.Sprintf("%x", h.Sum(nil)), fmt.Sprintf("%x", sha256.Sum256([]byte(tt.body))); got != want {
+				t.Errorf("checksum:got %s, want %s", got, want)
+			}
+			if entries, _ := os.ReadDir(tmp); len(entries) != 0 {
+				t.Errorf("the temporary file is not removed: %v", entries)
+			}
+		})
+	}
+
+	t.Run("SEQUENTIAL", func(t *testing.T) {
+		first, second, err := NewRequest(NewClient()).Get(srv.URL).Split()
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		a, _ := io.ReadAll(first)
+		first.Close()
+		b, _ := io.ReadAll(second)
+		second.Close()
+		if string(a) != small || string(b) != small {
+			t.Errorf("got %q and %q", a, b)
+		}
+	})
+
+	if _, _, err := NewRequest(NewClient()).Get("").Split(); err == nil {
+		t.Errorf("failed request:got nil error")
+	}
+}
@@ -0,0 +1,171 @@
+package www
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultEventRetry is the reconnection time of Events until the server
+// sets one.
+const DefaultEventRetry = 3 * time.Second
+
+// Event is a server-sent event.
+type Event struct {
+	// the last event ID of the stream, kept by the events without one
+	ID string
+	// "message" by default
+	Event string
+	// the data lines joined with newlines
+	Data string
+	// the reconnection time the event set, 0 if none
+	Retry time.Duration
+}
+
+// Events calls fn with every server-sent event of a text/event-stream
+// body as it is read. When the stream ends or breaks, the request is sent
+// again as with Clone after the reconnection time, with a Last-Event-ID
+// header, and the events go on; a reconnection that fails or does not
+// answer 200 with an event stream returns the error, a 204 stops the
+// stream. fn returns ErrStopIteration to stop, any other error stops and
+// is returned, as does the context of the request once done. The body is
+// closed.
+func (resp *Response) Events(fn func(Event) error) error {
+	if resp.err != nil {
+		return resp.err
+	}
+	if resp.Response == nil || resp.request == nil {
+		return ErrorNoResponse
+	}
+	r := resp.request
+	ctx := r.context()
+	s := &eventStream{retry: DefaultEventRetry}
+
+	for {
+		if resp.StatusCode == http.StatusNoContent {
+			resp.Body.Close()
+			return nil
+		}
+		if err := resp.checkEventStream(); err != nil {
+			resp.Body.Close()
+			return err
+		}
+		err := s.read(resp, fn)
+		drainBody(resp.Body)
+		if errors.Is(err, ErrStopIteration) {
+			return nil
+		}
+		if err != nil && !errors.Is(err, errEventStreamBroken) {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if err := r.client.sleep(ctx, s.retry); err != nil {
+			return err
+		}
+		var header http.Header
+		if s.lastID != "" {
+			header = http.Header{"Last-Event-Id": {s.lastID}}
+		}
+		r = r.Clone()
+		if resp = r.Do(r.method, r.uri, header); resp.err != nil {
+			return resp.err
+		}
+	}
+}
+
+// errEventStreamBroken marks a read error of the body, the stream is then
+// reconnected as when it ends.
+var errEventStreamBroken = errors.New("event stream broken")
+
+func (resp *Response) checkEventStream() error {
+	if resp.StatusCode != http.StatusOK {
+		return resp.statusError()
+	}
+	if mediaType := resp.mediaType(); mediaType != "text/event-stream" {
+		return fmt.Errorf("%w: %q, want text/event-stream", ErrorUnexpectedContentType, mediaType)
+	}
+	return nil
+}
+
+// eventStream keeps the state of Events across the reconnections.
+type eventStream struct {
+	lastID string
+	retry  time.Duration
+}
+
+// read parses the body by the rules of the HTML standard, an event not
+// ended by a blank line is dropped.
+func (s *eventStream) read(resp *Response, fn func(Event) error) error {
+	reader, err := resp.bodyReader()
+	if err != nil {
+		return err
+	}
+	br := bufio.NewReader(reader)
+
+	var event Event
+	var data strings.Builder
+	hasData := false
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			if errors.Is(err, ErrBodyTooLarge) {
+				return err
+			}
+			return fmt.Errorf("%w: %v", errEventStreamBroken, err)
+		}
+		line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+
+		if line == "" {
+			if hasData {
+				event.ID = s.lastID
+				if event.Event == "" {
+					event.Event = "message"
+				}
+				event.Data = data.String()
+				if err := fn(event); err != nil {
+					return err
+				}
+			}
+			event = Event{}
+			data.Reset()
+			hasData = false
+			continue
+		}
+		if strings.HasPrefix(line, ":") { // a comment, e.g. a keep-alive
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			event.Event = value
+		case "data":
+			if hasData {
+				data.WriteByte('\n')
+			}
+			data.WriteString(value)
+			hasData = true
+		case "id":
+			if !strings.ContainsRune(value, 0) {
+				s.lastID = value
+			}
+		case "retry":
+			if ms, err := strconv.ParseUint(value, 10, 63); err == nil {
+				s.retry = time.Duration(ms) * time.Millisecond
+				event.Retry = s.retry
+			}
+		}
+	}
+}
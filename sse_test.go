@@ -0,0 +1,114 @@
+package www
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestEvents(t *testing.T) {
+
+	var conns int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&conns, 1)
+		w.Header().Set("Content-Type", "text/event-stream")
+		switch {
+		case r.URL.Path == "/json":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte("{}"))
+		case r.URL.Path == "/forever":
+			for i := 0; ; i++ {
+				if _, err := fmt.Fprintf(w, "data: %d\n\n", i); err != nil {
+					return
+				}
+				w.(http.Flusher).Flush()
+				time.Sleep(time.Millisecond)
+			}
+		case n == 1:
+			w.Write([]byte("retry: 50\n\ndata: a\n\nid: 2\nevent: update\ndata: b\ndata: c\n\n: ping\ndata: dropped"))
+		case r.URL.Path == "/done":
+			w.WriteHeader(http.StatusNoContent)
+		case r.URL.Path == "/fail":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			fmt.Fprintf(w, "data: last %s\r\n\r\n", r.Header.Get("Last-Event-ID"))
+		}
+	}))
+	defer srv.Close()
+
+	t.Run("RECONNECT", func(t *testing.T) {
+		atomic.StoreInt32(&conns, 0)
+		clock := &fakeClock{now: time.Unix(0, 0)}
+		var events []Event
+		err := NewRequest(NewClient().WithClock(clock)).Get(srv.URL).Events(func(e Event) error {
+			events = append(events, e)
+			if len(events) == 3 {
+				return ErrStopIteration
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		want := []Event{
+			{Event: "message", Data: "a"},
+			{ID: "2", Event: "update", Data: "b\nc"},
+			{ID: "2", Event: "message", Data: "last 2"},
+		}
+		if fmt.Sprint(events) != fmt.Sprint(want) {
+			t.Errorf("Events:got %q, want %q", events, want)
+		}
+		if got, want := fmt.Sprint(clock.slept), "[50ms]"; got != want {
+			t.Errorf("Retry:got %v, want %v", got, want)
+		}
+	})
+
+	t.Run("NO CONTENT", func(t *testing.T) {
+		atomic.StoreInt32(&conns, 0)
+		var n int
+		err := NewRequest(NewClient().WithClock(&fakeClock{})).Get(srv.URL + "/done").Events(func(Event) error {
+			n++
+			return nil
+		})
+		if err != nil || n != 2 {
+			t.Errorf("got %d events, %v, want 2, <nil>", n, err)
+		}
+	})
+
+	t.Run("FAILED", func(t *testing.T) {
+		atomic.StoreInt32(&conns, 0)
+		err := NewRequest(NewClient().WithClock(&fakeClock{})).Get(srv.URL + "/fail").Events(func(Event) error {
+			return nil
+		})
+		var httpErr *HTTPError
+		if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusServiceUnavailable {
+			t.Errorf("got %v, want a 503 HTTPError", err)
+		}
+	})
+
+	t.Run("CONTEXT", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		err := NewRequest(NewClient()).WithContext(ctx).Get(srv.URL + "/forever").Events(func(Event) error {
+			cancel()
+			return nil
+		})
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("got %v, want %v", err, context.Canceled)
+		}
+	})
+
+	t.Run("NOT A STREAM", func(t *testing.T) {
+		err := NewRequest(NewClient()).Get(srv.URL + "/json").Events(func(Event) error {
+			return nil
+		})
+		if !errors.Is(err, ErrorUnexpectedContentType) {
+			t.Errorf("got %v, want %v", err, ErrorUnexpectedContentType)
+		}
+	})
+}
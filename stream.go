@@ -0,0 +1,72 @@
+package www
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrStopIteration is returned by the callback of JSONArray or JSONLines
+// to stop reading the stream, the decoder then returns nil.
+var ErrStopIteration = errors.New("stop iteration")
+
+var ErrorNotJSONArray = errors.New("the body is not a JSON array")
+
+// JSONArray calls fn with every element of a JSON array body as it is
+// decoded, without reading the whole body first. fn returns
+// ErrStopIteration to stop early, e.g. after the first N matches, any
+// other error stops and is returned. The body is closed.
+func (resp *Response) JSONArray(fn func(json.RawMessage) error) error {
+	return resp.decodeStream(fn, true)
+}
+
+// JSONLines calls fn with every value of a newline-delimited JSON
+// (NDJSON) body as it is decoded, like JSONArray. Both return the error
+// of the request context once it is done.
+func (resp *Response) JSONLines(fn func(json.RawMessage) error) error {
+	return resp.decodeStream(fn, false)
+}
+
+func (resp *Response) decodeStream(fn func(json.RawMessage) error, array bool) error {
+	if resp.err != nil {
+		return resp.err
+	}
+	if resp.Response == nil {
+		return ErrorNoResponse
+	}
+	// a stopped stream is drained a little so the connection can be reused
+	defer drainBody(resp.Body)
+
+	reader, err := resp.bodyReader()
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(reader)
+	if array {
+		if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+			return fmt.Errorf("%w: starts with %v", ErrorNotJSONArray, tok)
+		}
+	}
+
+	for !array || dec.More() {
+		var item json.RawMessage
+		if err := dec.Decode(&item); err != nil {
+			if err == io.EOF && !array {
+				return nil
+			}
+			if resp.Request != nil && resp.Request.Context().Err() != nil {
+				return resp.Request.Context().Err()
+			}
+			return err
+		}
+		if err := fn(item); err != nil {
+			if errors.Is(err, ErrStopIteration) {
+				return nil
+			}
+			return err
+		}
+	}
+	_, err = dec.Token() // the closing ]
+	return err
+}
@@ -0,0 +1,97 @@
+package www
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestStopIteration(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lines := r.URL.Path == "/lines"
+		if !lines {
+			w.Write([]byte("["))
+		}
+		for i := 0; i < 100000; i++ {
+			sep := ","
+			if lines {
+				sep = "\n"
+			} else if i == 0 {
+				sep = ""
+			}
+			if _, err := fmt.Fprintf(w, `%s{"id":%d}`, sep, i); err != nil {
+				return
+			}
+		}
+		if !lines {
+			w.Write([]byte("]"))
+		}
+	}))
+	defer srv.Close()
+
+	decoders := map[string]func(*Response, func(json.RawMessage) error) error{
+		"/array": (*Response).JSONArray,
+		"/lines": (*Response).JSONLines,
+	}
+	for path, decode := range decoders {
+		path, decode := path, decode
+		t.Run(path, func(t *testing.T) {
+			var ids []int
+			err := decode(NewRequest(NewClient()).Get(srv.URL+path), func(raw json.RawMessage) error {
+				var item struct{ ID int }
+				if err := json.Unmarshal(raw, &item); err != nil {
+					return err
+				}
+				ids = append(ids, item.ID)
+				if len(ids) == 2 {
+					return ErrStopIteration
+				}
+				return nil
+			})
+			if err != nil || fmt.Sprint(ids) != "[0 1]" {
+				t.Errorf("stopped:got %v, %v, want [0 1]", ids, err)
+			}
+
+			var n int
+			errBad := errors.New("bad item")
+			err = decode(NewRequest(NewClient()).Get(srv.URL+path), func(json.RawMessage) error {
+				if n++; n == 3 {
+					return errBad
+				}
+				return nil
+			})
+			if err != errBad {
+				t.Errorf("callback error:got %v, want %v", err, errBad)
+			}
+
+			n = 0
+			err = decode(NewRequest(NewClient()).Get(srv.URL+path), func(json.RawMessage) error {
+				n++
+				return nil
+			})
+			if err != nil || n != 100000 {
+				t.Errorf("whole stream:got %d items, %v", n, err)
+			}
+		})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	err := NewRequest(NewClient()).WithContext(ctx).Get(srv.URL + "/lines").JSONLines(func(json.RawMessage) error {
+		cancel()
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("canceled:got %v, want %v", err, context.Canceled)
+	}
+
+	err = NewRequest(NewClient()).Get(srv.URL + "/lines").JSONArray(func(json.RawMessage) error { return nil })
+	if !errors.Is(err, ErrorNotJSONArray) {
+		t.Errorf("not an array:got %v, want %v", err, ErrorNotJSONArray)
+	}
+}
@@ -0,0 +1,119 @@
+package www
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+var ErrorResponseTimeout = errors.New("no response headers within the response timeout")
+
+// ResponseTimeout bounds the time from writing the request to receiving
+// the first byte of the response: a server that accepts the connection
+// but never answers fails with ErrorResponseTimeout. Unlike the client
+// Timeout, dialing, the TLS handshake and reading the body are not
+// counted. Every redirect hop gets the full timeout.
+func (r *Request) ResponseTimeout(d time.Duration) *Request {
+	r.responseTimeout = d
+	return r
+}
+
+// WithTimeout bounds the whole exchange, from dialing to reading the end
+// of the body, retries and their waits included, with a deadline derived
+// from the WithContext context: the request or the body reading then
+// fails with context.DeadlineExceeded. Every Follow gets the full
+// timeout again.
+func (r *Request) WithTimeout(d time.Duration) *Request {
+	r.timeout = d
+	return r
+}
+
+// withTimeout sets the WithTimeout deadline on the request context, the
+// returned function makes closing the body of the response release it.
+func (r *Request) withTimeout() func(*Response) {
+	ctx, cancel := context.WithTimeout(r.Request.Context(), r.timeout)
+	r.Request = r.Request.WithContext(ctx)
+
+	return func(resp *Response) {
+		if resp.Response == nil {
+			cancel()
+			return
+		}
+		resp.Body = &cancelBody{resp.Body, cancel}
+	}
+}
+
+type responseTimer struct {
+	mu       sync.Mutex
+	d        time.Duration
+	timer    *time.Timer
+	cancel   context.CancelFunc
+	timedOut bool
+}
+
+func (rt *responseTimer) start(httptrace.WroteRequestInfo) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	rt.timer = time.AfterFunc(rt.d, func() {
+		rt.mu.Lock()
+		rt.timedOut = true
+		rt.mu.Unlock()
+		rt.cancel()
+	})
+}
+
+func (rt *responseTimer) stop() {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	if rt.timer != nil {
+		rt.timer.Stop()
+	}
+}
+
+func (rt *responseTimer) expired() bool {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	return rt.timedOut
+}
+
+// withResponseTimeout arms the timer on the request context, the returned
+// function finishes the exchange: it reports the timeout and makes closing
+// the body release the context.
+func (r *Request) withResponseTimeout() func(*http.Response, error) error {
+	ctx, cancel := context.WithCancel(r.Request.Context())
+	rt := &responseTimer{d: r.responseTimeout, cancel: cancel}
+	ctx = httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		WroteRequest:         rt.start,
+		GotFirstResponseByte: rt.stop,
+	})
+	r.Request = r.Request.WithContext(ctx)
+
+	return func(resp *http.Response, err error) error {
+		rt.stop()
+		if err != nil {
+			cancel()
+			if rt.expired() {
+				return fmt.Errorf("%w (%s): %v", ErrorResponseTimeout, rt.d, err)
+			}
+			return err
+		}
+		resp.Body = &cancelBody{resp.Body, cancel}
+		return nil
+	}
+}
+
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
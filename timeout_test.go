@@ -0,0 +1,40 @@
+package www
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestResponseTimeout(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/stall" {
+			time.Sleep(500 * time.Millisecond)
+		}
+		w.Write([]byte("head"))
+		w.(http.Flusher).Flush()
+		time.Sleep(300 * time.Millisecond)
+		w.Write([]byte("body"))
+	}))
+	defer srv.Close()
+
+	t.Run("STALL", func(t *testing.T) {
+		resp := NewRequest(NewClient()).ResponseTimeout(100 * time.Millisecond).Get(srv.URL + "/stall")
+		if !errors.Is(resp.Error(), ErrorResponseTimeout) {
+			t.Errorf("Error:got %v, want %v", resp.Error(), ErrorResponseTimeout)
+		}
+	})
+
+	t.Run("SLOW BODY", func(t *testing.T) {
+		resp := NewRequest(NewClient()).ResponseTimeout(100 * time.Millisecond).Get(srv.URL)
+		if resp.Error() != nil {
+			t.Fatalf("%v", resp.Error())
+		}
+		if got := resp.Text(); got != "headbody" {
+			t.Errorf("got %q, want %q", got, "headbody")
+		}
+	})
+}
@@ -0,0 +1,105 @@
+package www
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+)
+
+var ErrorNoCertificates = errors.New("no PEM certificates found")
+
+// The options below configure the *http.Transport of the client in
+// place, keeping its other settings; a client with another RoundTripper
+// fails its requests with ErrorNotTransport.
+
+// WithProxy sends the requests through the proxy at proxyURL (http,
+// https or socks5, credentials in the URL are sent to the proxy), an
+// empty proxyURL connects directly, ignoring HTTP_PROXY and friends.
+func (cl *StandardClient) WithProxy(proxyURL string) *StandardClient {
+	t, err := cl.httpTransport()
+	if err != nil {
+		cl.err = err
+		return cl
+	}
+	if proxyURL == "" {
+		t.Proxy = nil
+		return cl
+	}
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		cl.err = err
+		return cl
+	}
+	t.Proxy = http.ProxyURL(u)
+	return cl
+}
+
+// WithTLSConfig replaces the TLS configuration of the transport with a
+// copy of config.
+func (cl *StandardClient) WithTLSConfig(config *tls.Config) *StandardClient {
+	t, err := cl.httpTransport()
+	if err != nil {
+		cl.err = err
+		return cl
+	}
+	t.TLSClientConfig = config.Clone()
+	return cl
+}
+
+// WithRootCAsFromFile verifies the server certificates against the PEM
+// certificates of the file instead of the system roots.
+func (cl *StandardClient) WithRootCAsFromFile(path string) *StandardClient {
+	pem, err := ioutil.ReadFile(path)
+	if err != nil {
+		cl.err = err
+		return cl
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		cl.err = fmt.Errorf("%w: %s", ErrorNoCertificates, path)
+		return cl
+	}
+	return cl.withTLS(func(config *tls.Config) {
+		config.RootCAs = pool
+	})
+}
+
+// WithClientCertFromFile presents the PEM certificate and key of the files
+// to the servers asking for a client certificate.
+func (cl *StandardClient) WithClientCertFromFile(certFile, keyFile string) *StandardClient {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		cl.err = err
+		return cl
+	}
+	return cl.withTLS(func(config *tls.Config) {
+		config.Certificates = append(config.Certificates, cert)
+	})
+}
+
+// WithInsecureSkipVerify accepts any server certificate, which makes TLS
+// open to man-in-the-middle attacks: for development only.
+func (cl *StandardClient) WithInsecureSkipVerify() *StandardClient {
+	return cl.withTLS(func(config *tls.Config) {
+		config.InsecureSkipVerify = true
+	})
+}
+
+// withTLS changes the TLS configuration of the transport, creating it
+// when needed.
+func (cl *StandardClient) withTLS(fn func(*tls.Config)) *StandardClient {
+	t, err := cl.httpTransport()
+	if err != nil {
+		cl.err = err
+		return cl
+	}
+	if t.TLSClientConfig == nil {
+		t.TLSClientConfig = &tls.Config{}
+	}
+	fn(t.TLSClientConfig)
+	return cl
+}
@@ -0,0 +1,111 @@
+package www
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writePEM(t *testing.T, name, blockType string, der []byte) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestTLSOptions(t *testing.T) {
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.PeerCertificates) > 0 {
+			w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+		}
+	}))
+	srv.TLS = &tls.Config{ClientAuth: tls.RequestClientCert}
+	srv.StartTLS()
+	defer srv.Close()
+
+	t.Run("ROOT CAS", func(t *testing.T) {
+		if resp := NewRequest(NewClient()).Get(srv.URL); resp.Error() == nil {
+			t.Errorf("system roots:got no error")
+		}
+		roots := writePEM(t, "roots.pem", "CERTIFICATE", srv.Certificate().Raw)
+		if resp := NewRequest(NewClient().WithRootCAsFromFile(roots)).Get(srv.URL); resp.Error() != nil {
+			t.Errorf("file roots:got %v", resp.Error())
+		}
+
+		empty := writePEM(t, "empty.pem", "NOTHING", nil)
+		if resp := NewRequest(NewClient().WithRootCAsFromFile(empty)).Get(srv.URL); !errors.Is(resp.Error(), ErrorNoCertificates) {
+			t.Errorf("no certificates:got %v, want %v", resp.Error(), ErrorNoCertificates)
+		}
+	})
+
+	t.Run("INSECURE", func(t *testing.T) {
+		if resp := NewRequest(NewClient().WithInsecureSkipVerify()).Get(srv.URL); resp.Error() != nil {
+			t.Errorf("got %v", resp.Error())
+		}
+	})
+
+	t.Run("CLIENT CERT", func(t *testing.T) {
+		key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(1),
+			Subject:      pkix.Name{CommonName: "www-client"},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		}
+		der, _ := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+		keyDER, _ := x509.MarshalPKCS8PrivateKey(key)
+		certFile := writePEM(t, "client.pem", "CERTIFICATE", der)
+		keyFile := writePEM(t, "client.key", "PRIVATE KEY", keyDER)
+
+		cl := NewClient().WithInsecureSkipVerify().WithClientCertFromFile(certFile, keyFile)
+		if got := NewRequest(cl).Get(srv.URL).Text(); got != "www-client" {
+			t.Errorf("got %q, want %q", got, "www-client")
+		}
+	})
+
+	t.Run("TLS CONFIG", func(t *testing.T) {
+		roots := x509.NewCertPool()
+		roots.AddCert(srv.Certificate())
+		config := &tls.Config{RootCAs: roots}
+		cl := NewClient().WithTLSConfig(config).WithServerName("example.com")
+		if resp := NewRequest(cl).Get(srv.URL); resp.Error() != nil {
+			t.Errorf("got %v", resp.Error())
+		}
+		if config.ServerName != "" {
+			t.Errorf("the config passed was changed")
+		}
+	})
+}
+
+func TestWithProxy(t *testing.T) {
+
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("proxied " + r.URL.String()))
+	}))
+	defer proxy.Close()
+
+	got := NewRequest(NewClient().WithProxy(proxy.URL)).Get("http://www-test.invalid/path").Text()
+	if want := "proxied http://www-test.invalid/path"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	cl := NewClient().WithTransport(roundTripFunc(nil)).WithProxy(proxy.URL)
+	if resp := NewRequest(cl).Get(proxy.URL); !errors.Is(resp.Error(), ErrorNotTransport) {
+		t.Errorf("other transport:got %v, want %v", resp.Error(), ErrorNotTransport)
+	}
+}
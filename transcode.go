@@ -0,0 +1,52 @@
+package www
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/transform"
+)
+
+// WithAutoTranscode makes Content, Text and the other readers return
+// UTF-8 for text responses (text/*, JSON, XML, JavaScript) declaring
+// another charset, binary responses are passed through untouched.
+// An optional acceptCharset is sent as the Accept-Charset header.
+func (cl *StandardClient) WithAutoTranscode(acceptCharset ...string) *StandardClient {
+	cl.autoTranscode = true
+	if len(acceptCharset) > 0 {
+		cl.acceptCharset = acceptCharset[0]
+	}
+	return cl
+}
+
+func isTextType(mediaType string) bool {
+	if strings.HasPrefix(mediaType, "text/") {
+		return true
+	}
+	switch mediaType {
+	case "application/json", "application/xml", "application/javascript":
+		return true
+	}
+	return strings.HasSuffix(mediaType, "+json") || strings.HasSuffix(mediaType, "+xml")
+}
+
+// transcode returns a reader converting the body to UTF-8 when the
+// response is text in another charset.
+func transcode(header http.Header, reader io.Reader) (io.Reader, bool) {
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil || !isTextType(mediaType) {
+		return reader, false
+	}
+	charset := strings.ToLower(params["charset"])
+	if charset == "" || charset == "utf-8" || charset == "utf8" || charset == "us-ascii" {
+		return reader, false
+	}
+	enc, err := htmlindex.Get(charset)
+	if err != nil {
+		return reader, false
+	}
+	return transform.NewReader(reader, enc.NewDecoder()), true
+}
@@ -0,0 +1,39 @@
+package www
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAutoTranscode(t *testing.T) {
+
+	cp1251 := []byte{0xcf, 0xf0, 0xe8, 0xe2, 0xe5, 0xf2} // "Привет"
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Accept-Charset", r.Header.Get("Accept-Charset"))
+		if r.URL.Path == "/text" {
+			w.Header().Set("Content-Type", "text/plain; charset=windows-1251")
+		} else {
+			w.Header().Set("Content-Type", "application/octet-stream; charset=windows-1251")
+		}
+		w.Write(cp1251)
+	}))
+	defer srv.Close()
+
+	cl := NewClient().WithAutoTranscode("utf-8")
+
+	resp := NewRequest(cl).Get(srv.URL + "/text")
+	if got := string(resp.Content()); got != "Привет" {
+		t.Errorf("text:got %q, want %q", got, "Привет")
+	}
+	if got := resp.Header.Get("X-Accept-Charset"); got != "utf-8" {
+		t.Errorf("Accept-Charset:got %q, want utf-8", got)
+	}
+
+	resp = NewRequest(cl).Get(srv.URL + "/binary")
+	if got := resp.Content(); !bytes.Equal(got, cp1251) {
+		t.Errorf("binary:got %x, want %x", got, cp1251)
+	}
+}
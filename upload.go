@@ -0,0 +1,42 @@
+package www
+
+import (
+	"io"
+	"net/http"
+)
+
+// OnUploadProgress calls fn after every chunk of the request body read by
+// the transport with the bytes sent so far and the size of the body, -1
+// when it is streamed (multipart, WithFile, ...) without a known length.
+// A retry starts over from zero.
+func (r *Request) OnUploadProgress(fn func(sent, total int64)) *Request {
+	r.onUpload = fn
+	return r
+}
+
+func (r *Request) trackUpload() {
+	if r.onUpload == nil || r.Request.Body == nil || r.Request.Body == http.NoBody {
+		return
+	}
+	total := r.Request.ContentLength
+	if total == 0 {
+		total = -1
+	}
+	r.Request.Body = &progressBody{ReadCloser: r.Request.Body, total: total, fn: r.onUpload}
+}
+
+type progressBody struct {
+	io.ReadCloser
+	sent  int64
+	total int64
+	fn    func(sent, total int64)
+}
+
+func (b *progressBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		b.sent += int64(n)
+		b.fn(b.sent, b.total)
+	}
+	return n, err
+}
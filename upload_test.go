@@ -0,0 +1,47 @@
+package www
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOnUploadProgress(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+	}))
+	defer srv.Close()
+
+	content := strings.Repeat("x", 1<<20)
+	path := filepath.Join(t.TempDir(), "upload.bin")
+	os.WriteFile(path, []byte(content), 0o666)
+
+	t.Run("MULTIPART", func(t *testing.T) {
+		file, _ := os.Open(path)
+		var calls int
+		var sent, total int64
+		NewRequest(NewClient()).AttachFile(file).OnUploadProgress(func(n, size int64) {
+			calls++
+			sent, total = n, size
+		}).Post(srv.URL)
+		if calls < 2 || sent <= int64(len(content)) || total != -1 {
+			t.Errorf("progress:got %d/%d in %d calls", sent, total, calls)
+		}
+	})
+
+	t.Run("BUFFERED", func(t *testing.T) {
+		var sent, total int64
+		NewRequest(NewClient()).WithSeeker(strings.NewReader(content), "text/plain").
+			OnUploadProgress(func(n, size int64) {
+				sent, total = n, size
+			}).Post(srv.URL)
+		if sent != int64(len(content)) || total != int64(len(content)) {
+			t.Errorf("progress:got %d/%d, want %d/%d", sent, total, len(content), len(content))
+		}
+	})
+}
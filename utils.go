@@ -1,14 +1,70 @@
 package www
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
 	"net/textproto"
+	"net/url"
 	"os"
 	"strings"
 )
 
+var (
+	ErrBodyTooLarge   = errors.New("the response body exceeds the maximum size")
+	ErrUploadTooLarge = errors.New("the request body exceeds the maximum upload size")
+)
+
+var ErrProtocolMismatch = errors.New("the server does not speak the protocol of the URL scheme")
+
+// protocolMismatch turns the cryptic transport errors of a plain HTTP
+// server behind an https URL (and of a TLS server behind an http URL)
+// into ErrProtocolMismatch with the likely fix. A Go TLS server answers
+// plain HTTP with a 400 response instead, that is not an error.
+func protocolMismatch(u *url.URL, err error) error {
+	if err == nil || u == nil {
+		return err
+	}
+
+	msg := err.Error()
+	scheme := ""
+	switch {
+	case strings.Contains(msg, "server gave HTTP response to HTTPS client"),
+		strings.Contains(msg, "first record does not look like a TLS handshake"):
+		scheme = "http"
+	case strings.Contains(msg, `malformed HTTP response "\x15\x03`),
+		strings.Contains(msg, `malformed HTTP response "\x16\x03`):
+		scheme = "https" // a TLS alert or handshake record
+	default:
+		return err
+	}
+
+	return fmt.Errorf("%w: %s://%s speaks %s, use an %s:// URL: %v",
+		ErrProtocolMismatch, u.Scheme, u.Host, strings.ToUpper(scheme), scheme, err)
+}
+
+// maxBytesReader fails with err once more than n bytes are read.
+type maxBytesReader struct {
+	r   io.Reader
+	n   int64 // bytes left
+	err error
+}
+
+func (l *maxBytesReader) Read(p []byte) (n int, err error) {
+	if int64(len(p)) > l.n+1 {
+		p = p[:l.n+1]
+	}
+	n, err = l.r.Read(p)
+	if int64(n) <= l.n {
+		l.n -= int64(n)
+		return n, err
+	}
+	n = int(l.n)
+	l.n = 0
+	return n, l.err
+}
+
 func MustOpen(f string) *os.File {
 	r, err := os.Open(f)
 	if err != nil {
@@ -0,0 +1,56 @@
+package www
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// CachedValidators makes the request conditional on a copy the caller
+// keeps: the etag is sent as If-None-Match and a non-zero lastModified as
+// If-Modified-Since. When the server answers 304 Not Modified and the
+// cached body is passed, the response is turned into a 200 carrying that
+// body, so Content and the other readers return it as if it was sent
+// again and FromCache reports true, NotModified reports true in both
+// cases.
+func (r *Request) CachedValidators(etag string, lastModified time.Time, cached ...[]byte) *Request {
+	r.etag = etag
+	r.lastModified = lastModified
+	r.cached = nil
+	if len(cached) > 0 {
+		r.cached = cached[0]
+	}
+	return r
+}
+
+func (r *Request) prepareValidators() {
+	if r.etag != "" {
+		r.Request.Header.Set("If-None-Match", r.etag)
+	}
+	if !r.lastModified.IsZero() {
+		r.Request.Header.Set("If-Modified-Since", r.lastModified.UTC().Format(http.TimeFormat))
+	}
+}
+
+func (resp *Response) useCached() {
+	if resp.Response == nil || resp.StatusCode != http.StatusNotModified || resp.request == nil {
+		return
+	}
+	resp.notModified = true
+	if resp.request.cached == nil {
+		return
+	}
+
+	resp.Body.Close()
+	resp.StatusCode = http.StatusOK
+	resp.Status = "200 OK"
+	resp.Body = ioutil.NopCloser(bytes.NewReader(resp.request.cached))
+	resp.ContentLength = int64(len(resp.request.cached))
+	resp.fromCache = true
+}
+
+// NotModified reports whether the server answered 304 Not Modified.
+func (resp Response) NotModified() bool {
+	return resp.notModified
+}
@@ -0,0 +1,55 @@
+package www
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCachedValidators(t *testing.T) {
+
+	modified := time.Date(2021, 9, 1, 0, 0, 0, 0, time.UTC)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` &&
+			r.Header.Get("If-Modified-Since") == modified.Format(http.TimeFormat) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Write([]byte("fresh"))
+	}))
+	defer srv.Close()
+
+	resp := NewRequest(NewClient()).
+		CachedValidators(`"v1"`, modified, []byte("cached")).
+		Get(srv.URL)
+	if resp.Error() != nil {
+		t.Fatalf("%v", resp.Error())
+	}
+	if !resp.NotModified() || !resp.FromCache() || resp.StatusCode != http.StatusOK {
+		t.Errorf("got %d, NotModified %v, FromCache %v", resp.StatusCode, resp.NotModified(), resp.FromCache())
+	}
+	if got := string(resp.Content()); got != "cached" {
+		t.Errorf("got %q, want %q", got, "cached")
+	}
+
+	var cached map[string]int
+	err := NewRequest(NewClient()).
+		CachedValidators(`"v1"`, modified, []byte(`{"version":1}`)).
+		Get(srv.URL).Json(&cached)
+	if err != nil || cached["version"] != 1 {
+		t.Errorf("Json:got %v, %v", cached, err)
+	}
+
+	resp = NewRequest(NewClient()).CachedValidators(`"v1"`, modified).Get(srv.URL)
+	if !resp.NotModified() || resp.FromCache() || resp.StatusCode != http.StatusNotModified {
+		t.Errorf("without body:got %d, NotModified %v, FromCache %v", resp.StatusCode, resp.NotModified(), resp.FromCache())
+	}
+
+	resp = NewRequest(NewClient()).CachedValidators(`"v0"`, time.Time{}, []byte("cached")).Get(srv.URL)
+	if resp.NotModified() || resp.FromCache() || string(resp.Content()) != "fresh" {
+		t.Errorf("stale:got NotModified %v, FromCache %v", resp.NotModified(), resp.FromCache())
+	}
+}
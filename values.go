@@ -0,0 +1,141 @@
+package www
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrorUnsupportedValues = errors.New("unsupported type of values")
+
+// Time formats of the time.Time fields of encoded structs (see Query).
+// Any other value is used as a time.Format layout.
+const (
+	TimeRFC3339   = "rfc3339"
+	TimeUnix      = "unix"
+	TimeUnixMilli = "unixmilli"
+)
+
+func formatTime(t time.Time, format string) string {
+	switch format {
+	case "", TimeRFC3339:
+		return t.Format(time.RFC3339)
+	case TimeUnix:
+		return strconv.FormatInt(t.Unix(), 10)
+	case TimeUnixMilli:
+		return strconv.FormatInt(t.UnixNano()/int64(time.Millisecond), 10)
+	default:
+		return t.Format(format)
+	}
+}
+
+// timeFormatOf returns the time format of a struct field: the `layout` tag
+// wins over a unix/unixmilli/rfc3339 option of the tag naming the field,
+// otherwise the fallback (the client setting) is used.
+func timeFormatOf(field reflect.StructField, tag string, fallback string) string {
+	if layout := field.Tag.Get("layout"); layout != "" {
+		return layout
+	}
+
+	opts := strings.Split(field.Tag.Get(tag), ",")
+	for _, opt := range opts[1:] {
+		switch opt {
+		case TimeRFC3339, TimeUnix, TimeUnixMilli:
+			return opt
+		}
+	}
+
+	return fallback
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// encodeStruct encodes the exported fields of a struct (or a pointer to
+// one) named by tag: `url:"name,omitempty"`, "-" skips a field, untagged
+// fields use their names. Slices give multiple values, time.Time fields
+// are formatted as told by timeFormatOf.
+func encodeStruct(v interface{}, tag string, timeFormat string) (url.Values, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return url.Values{}, nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%w: %T", ErrorUnsupportedValues, v)
+	}
+
+	values := url.Values{}
+	typ := rv.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.PkgPath != "" { // unexported
+			continue
+		}
+		opts := strings.Split(field.Tag.Get(tag), ",")
+		name := opts[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		omitEmpty := false
+		for _, opt := range opts[1:] {
+			if opt == "omitempty" {
+				omitEmpty = true
+			}
+		}
+
+		fv := rv.Field(i)
+		if omitEmpty && fv.IsZero() {
+			continue
+		}
+		for fv.Kind() == reflect.Ptr {
+			if fv.IsNil() {
+				break
+			}
+			fv = fv.Elem()
+		}
+		if fv.Kind() == reflect.Ptr { // nil
+			continue
+		}
+
+		format := timeFormatOf(field, tag, timeFormat)
+		if fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() != reflect.Uint8 {
+			for j := 0; j < fv.Len(); j++ {
+				values.Add(name, formatValue(fv.Index(j), format))
+			}
+			continue
+		}
+		values.Add(name, formatValue(fv, format))
+	}
+
+	return values, nil
+}
+
+func formatValue(v reflect.Value, timeFormat string) string {
+	if v.Type() == timeType {
+		return formatTime(v.Interface().(time.Time), timeFormat)
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits())
+	case reflect.Slice: // []byte
+		return string(v.Bytes())
+	}
+	return fmt.Sprint(v.Interface())
+}
@@ -0,0 +1,44 @@
+package www
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTimeFormat(t *testing.T) {
+
+	ts := time.Date(2021, 9, 1, 12, 30, 0, 500*int(time.Millisecond), time.UTC)
+
+	type params struct {
+		Default time.Time `url:"default"`
+		RFC3339 time.Time `url:"rfc,rfc3339"`
+		Unix    time.Time `url:"unix,unix"`
+		Millis  time.Time `url:"millis,unixmilli"`
+		Layout  time.Time `url:"layout" layout:"2006-01-02"`
+	}
+
+	tests := []struct {
+		field    string
+		fallback string
+		want     string
+	}{
+		{"Default", "", "2021-09-01T12:30:00Z"},
+		{"Default", TimeUnix, "1630499400"},
+		{"RFC3339", TimeUnix, "2021-09-01T12:30:00Z"},
+		{"Unix", "", "1630499400"},
+		{"Millis", "", "1630499400500"},
+		{"Layout", TimeUnix, "2021-09-01"},
+	}
+
+	typ := reflect.TypeOf(params{})
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			field, _ := typ.FieldByName(tt.field)
+			got := formatTime(ts, timeFormatOf(field, "url", tt.fallback))
+			if got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -41,7 +41,9 @@ func TestWWW(t *testing.T) {
 				t.Errorf("StatusCode:got %d, want 200", resp.StatusCode)
 			} else {
 				t.Logf("%s", resp.Status)
-				t.Logf("%s", resp.Json())
+				var data map[string]interface{}
+				resp.Json(&data)
+				t.Logf("%v", data)
 				t.Logf("%s", r.Headers())
 			}
 		}
@@ -63,7 +65,9 @@ func TestWWW(t *testing.T) {
 				t.Errorf("StatusCode:got %d, want 200", resp.StatusCode)
 			} else {
 				t.Logf("%s", resp.Status)
-				t.Logf("%s", resp.Json())
+				var data map[string]interface{}
+				resp.Json(&data)
+				t.Logf("%v", data)
 				t.Logf("%s", r.Headers())
 			}
 		}
@@ -85,7 +89,9 @@ func TestWWW(t *testing.T) {
 				t.Errorf("StatusCode:got %d, want 200", resp.StatusCode)
 			} else {
 				t.Logf("%s", resp.Status)
-				t.Logf("%s", resp.Json())
+				var data map[string]interface{}
+				resp.Json(&data)
+				t.Logf("%v", data)
 				t.Logf("%s", r.Headers())
 			}
 		}
@@ -144,7 +150,9 @@ func TestWWW(t *testing.T) {
 					t.Errorf("StatusCode:got %d, want 200", resp.StatusCode)
 				} else {
 					t.Logf("%s", resp.Status)
-					t.Logf("%s", resp.Json())
+					var data map[string]interface{}
+					resp.Json(&data)
+					t.Logf("%v", data)
 					t.Logf("%s", r.Headers())
 				}
 			}